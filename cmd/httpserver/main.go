@@ -1,35 +1,63 @@
 package main
 
 import (
+	"context"
 	"log"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"surya.httpfromtcp/internal/request"
 	"surya.httpfromtcp/internal/response"
 	"surya.httpfromtcp/internal/server"
 )
 
-const port = 42069
+const (
+	port            = 42069
+	staticDir       = "static"
+	serverName      = "httpfromtcp"
+	shutdownTimeout = 10 * time.Second
+)
 
 func main() {
-	srv, err := server.Serve(port, handleRequest)
+	router := server.NewRouter()
+	router.HandleFunc("/yourproblem", handleYourProblem)
+	router.HandleFunc("/myproblem", handleMyProblem)
+	router.Handle("GET /static/{path...}", server.NewFileServer("/static/", staticDir, server.WithDirectoryListing()))
+	router.HandleFunc("/{path...}", handleSuccess)
+
+	logger := log.Default()
+	handler := server.Chain(router,
+		server.Recover(logger),
+		server.RequestID(),
+		server.Logger(logger),
+		server.Timing(),
+		server.Compress(server.DefaultCompressMinSize),
+		server.Decompress(server.DefaultMaxDecodedBodyBytes),
+	)
+
+	srv, err := server.Serve(port, handler, server.WithServerName(serverName))
 	if err != nil {
 		log.Fatalf("Error starting server: %v", err)
 	}
-	defer srv.Close()
 	log.Println("Server started on port", port)
 
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 	<-sigChan
+
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	if err := srv.Shutdown(ctx); err != nil {
+		log.Printf("Error during shutdown: %v", err)
+	}
 	log.Println("Server gracefully stopped")
 }
 
-func handleRequest(w *response.Writer, req *request.Request) {
-	if req.RequestLine.RequestTarget == "/yourproblem" {
-		body := []byte(`<html>
+func handleYourProblem(w *response.Writer, req *request.Request) {
+	body := []byte(`<html>
   <head>
     <title>400 Bad Request</title>
   </head>
@@ -39,16 +67,15 @@ func handleRequest(w *response.Writer, req *request.Request) {
   </body>
 </html>
 `)
-		headers := response.GetDefaultHeaders(len(body))
-		headers.Set("content-type", "text/html")
-		w.WriteStatusLine(response.StatusBadRequest)
-		w.WriteHeaders(headers)
-		w.WriteBody(body)
-		return
-	}
+	headers := response.GetDefaultHeaders(len(body))
+	headers.Set("content-type", "text/html")
+	w.WriteStatusLine(response.StatusBadRequest)
+	w.WriteHeaders(headers)
+	w.WriteBody(body)
+}
 
-	if req.RequestLine.RequestTarget == "/myproblem" {
-		body := []byte(`<html>
+func handleMyProblem(w *response.Writer, req *request.Request) {
+	body := []byte(`<html>
   <head>
     <title>500 Internal Server Error</title>
   </head>
@@ -58,14 +85,14 @@ func handleRequest(w *response.Writer, req *request.Request) {
   </body>
 </html>
 `)
-		headers := response.GetDefaultHeaders(len(body))
-		headers.Set("content-type", "text/html")
-		w.WriteStatusLine(response.StatusInternalServerError)
-		w.WriteHeaders(headers)
-		w.WriteBody(body)
-		return
-	}
+	headers := response.GetDefaultHeaders(len(body))
+	headers.Set("content-type", "text/html")
+	w.WriteStatusLine(response.StatusInternalServerError)
+	w.WriteHeaders(headers)
+	w.WriteBody(body)
+}
 
+func handleSuccess(w *response.Writer, req *request.Request) {
 	body := []byte(`<html>
   <head>
     <title>200 OK</title>
@@ -37,12 +37,19 @@ func main() {
 		fmt.Printf("- Version: %s\n", req.RequestLine.HttpVersion)
 
 		fmt.Println("Headers:")
-		for key, value := range req.Headers {
+		for key, value := range req.Headers.All() {
 			fmt.Printf("- %s: %s\n", key, value)
 		}
 
+		body, err := req.ReadBody()
+		if err != nil {
+			log.Printf("failed to read body: %s", err)
+			conn.Close()
+			continue
+		}
+
 		fmt.Println("Body:")
-		fmt.Println(string(req.Body))
+		fmt.Println(string(body))
 
 		conn.Close()
 		fmt.Println("connection closed")
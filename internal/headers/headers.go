@@ -3,21 +3,129 @@ package headers
 import (
 	"bytes"
 	"errors"
+	"fmt"
+	"iter"
+	"slices"
 	"strings"
 )
 
-type Headers map[string]string
+var ErrInvalidHeader = errors.New("invalid header")
 
-func NewHeaders() Headers {
-	return make(Headers)
+type field struct {
+	name  string
+	value string
 }
 
-func (h Headers) Get(key string) string {
-	return h[strings.ToLower(key)]
+type Headers struct {
+	fields []field
 }
 
-func (h Headers) Set(key, value string) {
-	h[strings.ToLower(key)] = value
+func NewHeaders() *Headers {
+	return &Headers{}
+}
+
+func (h *Headers) Get(key string) string {
+	if h == nil {
+		return ""
+	}
+
+	for _, f := range h.fields {
+		if strings.EqualFold(f.name, key) {
+			return f.value
+		}
+	}
+	return ""
+}
+
+func (h *Headers) Values(key string) []string {
+	if h == nil {
+		return nil
+	}
+
+	var values []string
+	for _, f := range h.fields {
+		if strings.EqualFold(f.name, key) {
+			values = append(values, f.value)
+		}
+	}
+	return values
+}
+
+func (h *Headers) Has(key string) bool {
+	if h == nil {
+		return false
+	}
+
+	return slices.ContainsFunc(h.fields, func(f field) bool {
+		return strings.EqualFold(f.name, key)
+	})
+}
+
+func (h *Headers) Add(key, value string) {
+	h.fields = append(h.fields, field{name: key, value: value})
+}
+
+func (h *Headers) Set(key, value string) {
+	matches := func(f field) bool {
+		return strings.EqualFold(f.name, key)
+	}
+
+	idx := slices.IndexFunc(h.fields, matches)
+	if idx == -1 {
+		h.Add(key, value)
+		return
+	}
+
+	h.fields[idx] = field{name: key, value: value}
+	rest := slices.DeleteFunc(h.fields[idx+1:], matches)
+	h.fields = h.fields[:idx+1+len(rest)]
+}
+
+func (h *Headers) Del(key string) {
+	h.fields = slices.DeleteFunc(h.fields, func(f field) bool {
+		return strings.EqualFold(f.name, key)
+	})
+}
+
+func (h *Headers) Clone() *Headers {
+	if h == nil {
+		return nil
+	}
+
+	return &Headers{fields: slices.Clone(h.fields)}
+}
+
+func (h *Headers) Len() int {
+	if h == nil {
+		return 0
+	}
+
+	return len(h.fields)
+}
+
+func (h *Headers) All() iter.Seq2[string, string] {
+	return func(yield func(string, string) bool) {
+		if h == nil {
+			return
+		}
+
+		for _, f := range h.fields {
+			if !yield(f.name, f.value) {
+				return
+			}
+		}
+	}
+}
+
+func (h *Headers) HasToken(key, token string) bool {
+	for _, value := range h.Values(key) {
+		for _, part := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(part), token) {
+				return true
+			}
+		}
+	}
+	return false
 }
 
 func isValidTokenChar(c byte) bool {
@@ -29,7 +137,50 @@ func isValidTokenChar(c byte) bool {
 		c == '`' || c == '|' || c == '~'
 }
 
-func (h Headers) Parse(data []byte) (n int, done bool, err error) {
+func IsToken(s string) bool {
+	if s == "" {
+		return false
+	}
+
+	for i := 0; i < len(s); i++ {
+		if !isValidTokenChar(s[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func IsCookieValue(value string) bool {
+	if len(value) >= 2 && value[0] == '"' && value[len(value)-1] == '"' {
+		value = value[1 : len(value)-1]
+	}
+
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		if c <= ' ' || c >= 0x7f || c == '"' || c == ',' || c == ';' || c == '\\' {
+			return false
+		}
+	}
+	return true
+}
+
+func ValidateField(name, value string) error {
+	if name == "" {
+		return fmt.Errorf("%w: empty field name", ErrInvalidHeader)
+	}
+
+	if !IsToken(name) {
+		return fmt.Errorf("%w: field name %q contains invalid character", ErrInvalidHeader, name)
+	}
+
+	if strings.ContainsAny(value, "\r\n\x00") {
+		return fmt.Errorf("%w: value of %q contains CR, LF or NUL", ErrInvalidHeader, name)
+	}
+
+	return nil
+}
+
+func (h *Headers) Parse(data []byte) (n int, done bool, err error) {
 	idx := bytes.Index(data, []byte("\r\n"))
 	if idx == -1 {
 		return 0, false, nil
@@ -39,39 +190,41 @@ func (h Headers) Parse(data []byte) (n int, done bool, err error) {
 		return 2, true, nil
 	}
 
-	line := strings.TrimSpace(string(data[:idx]))
+	if bytes.ContainsAny(data[:idx], "\r\n\x00") {
+		return 0, false, fmt.Errorf("%w: field line contains bare CR, LF or NUL", ErrInvalidHeader)
+	}
+
+	if data[0] == ' ' || data[0] == '\t' {
+		return 0, false, fmt.Errorf("%w: field line starts with whitespace", ErrInvalidHeader)
+	}
+
+	line := string(data[:idx])
 
 	colonIdx := strings.Index(line, ":")
 	if colonIdx == -1 {
-		return 0, false, errors.New("invalid header: no colon found")
+		return 0, false, fmt.Errorf("%w: no colon found", ErrInvalidHeader)
 	}
 
 	key := line[:colonIdx]
 	value := line[colonIdx+1:]
 
 	if strings.TrimSpace(key) != key {
-		return 0, false, errors.New("invalid header: space between field name and colon")
+		return 0, false, fmt.Errorf("%w: space between field name and colon", ErrInvalidHeader)
 	}
 
 	if key == "" {
-		return 0, false, errors.New("invalid header: empty field name")
+		return 0, false, fmt.Errorf("%w: empty field name", ErrInvalidHeader)
 	}
 
 	for i := 0; i < len(key); i++ {
 		if !isValidTokenChar(key[i]) {
-			return 0, false, errors.New("invalid header: field name contains invalid character")
+			return 0, false, fmt.Errorf("%w: field name contains invalid character", ErrInvalidHeader)
 		}
 	}
 
-	key = strings.ToLower(key)
-
 	value = strings.TrimSpace(value)
 
-	if existingValue, exists := h[key]; exists {
-		h[key] = existingValue + ", " + value
-	} else {
-		h[key] = value
-	}
+	h.Add(key, value)
 
 	return idx + 2, false, nil
 }
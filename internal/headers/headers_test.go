@@ -13,7 +13,7 @@ func TestHeadersParse(t *testing.T) {
 	n, done, err := headers.Parse(data)
 	require.NoError(t, err)
 	require.NotNil(t, headers)
-	assert.Equal(t, "localhost:42069", headers["host"])
+	assert.Equal(t, "localhost:42069", headers.Get("host"))
 	assert.Equal(t, 23, n)
 	assert.False(t, done)
 
@@ -22,52 +22,52 @@ func TestHeadersParse(t *testing.T) {
 	n, done, err = headers.Parse(data)
 	require.NoError(t, err)
 	require.NotNil(t, headers)
-	assert.Equal(t, "localhost:42069", headers["host"])
+	assert.Equal(t, "localhost:42069", headers.Get("host"))
 	assert.False(t, done)
 
 	headers = NewHeaders()
-	headers["existing"] = "value"
+	headers.Set("existing", "value")
 	data = []byte("Host: localhost:42069\r\n")
 	n, done, err = headers.Parse(data)
 	require.NoError(t, err)
-	assert.Equal(t, "localhost:42069", headers["host"])
-	assert.Equal(t, "value", headers["existing"])
+	assert.Equal(t, "localhost:42069", headers.Get("host"))
+	assert.Equal(t, "value", headers.Get("existing"))
 	assert.False(t, done)
 
 	data = []byte("User-Agent: curl/7.81.0\r\n\r\n")
 	n, done, err = headers.Parse(data)
 	require.NoError(t, err)
-	assert.Equal(t, "curl/7.81.0", headers["user-agent"])
-	assert.Equal(t, "localhost:42069", headers["host"])
-	assert.Equal(t, "value", headers["existing"])
+	assert.Equal(t, "curl/7.81.0", headers.Get("user-agent"))
+	assert.Equal(t, "localhost:42069", headers.Get("host"))
+	assert.Equal(t, "value", headers.Get("existing"))
 	assert.False(t, done)
 
 	headers = NewHeaders()
 	data = []byte("Content-Length: 1234\r\n")
 	n, done, err = headers.Parse(data)
 	require.NoError(t, err)
-	assert.Equal(t, "1234", headers["content-length"])
+	assert.Equal(t, "1234", headers.Get("content-length"))
 	assert.False(t, done)
 
 	headers = NewHeaders()
 	data = []byte("ACCEPT: application/json\r\n")
 	n, done, err = headers.Parse(data)
 	require.NoError(t, err)
-	assert.Equal(t, "application/json", headers["accept"])
+	assert.Equal(t, "application/json", headers.Get("accept"))
 	assert.False(t, done)
 
 	headers = NewHeaders()
-	headers["set-person"] = "lane-loves-go"
+	headers.Set("set-person", "lane-loves-go")
 	data = []byte("Set-Person: prime-loves-zig\r\n")
 	n, done, err = headers.Parse(data)
 	require.NoError(t, err)
-	assert.Equal(t, "lane-loves-go, prime-loves-zig", headers["set-person"])
+	assert.Equal(t, []string{"lane-loves-go", "prime-loves-zig"}, headers.Values("set-person"))
 	assert.False(t, done)
 
 	data = []byte("Set-Person: tj-loves-ocaml\r\n")
 	n, done, err = headers.Parse(data)
 	require.NoError(t, err)
-	assert.Equal(t, "lane-loves-go, prime-loves-zig, tj-loves-ocaml", headers["set-person"])
+	assert.Equal(t, []string{"lane-loves-go", "prime-loves-zig", "tj-loves-ocaml"}, headers.Values("set-person"))
 	assert.False(t, done)
 
 	headers = NewHeaders()
@@ -90,4 +90,87 @@ func TestHeadersParse(t *testing.T) {
 	require.Error(t, err)
 	assert.Equal(t, 0, n)
 	assert.False(t, done)
+
+	for _, line := range []string{
+		"X-A: a\rb\r\n\r\n",
+		"X-A: a\x00c\r\n\r\n",
+		"X-A: a\nX-B: b\r\n\r\n",
+		"X-A: a\r\r\n\r\n",
+		" X-A: a\r\n\r\n",
+		"\tX-A: a\r\n\r\n",
+	} {
+		headers = NewHeaders()
+		n, _, err = headers.Parse([]byte(line))
+		assert.ErrorIs(t, err, ErrInvalidHeader, line)
+		assert.Equal(t, 0, n)
+		assert.Equal(t, 0, headers.Len())
+	}
+}
+
+func TestHeadersHasToken(t *testing.T) {
+	headers := NewHeaders()
+	headers.Set("Connection", "keep-alive, Close")
+	assert.True(t, headers.HasToken("connection", "close"))
+	assert.True(t, headers.HasToken("CONNECTION", "keep-alive"))
+	assert.False(t, headers.HasToken("connection", "upgrade"))
+	assert.False(t, headers.HasToken("transfer-encoding", "chunked"))
+
+	headers.Set("Connection", "closed")
+	assert.False(t, headers.HasToken("connection", "close"))
+}
+
+func TestHeadersMultiValue(t *testing.T) {
+	headers := NewHeaders()
+	for _, line := range []string{
+		"Host: localhost:42069\r\n",
+		"Set-Cookie: session=abc; Expires=Wed, 21 Oct 2015 07:28:00 GMT\r\n",
+		"X-Trace: one\r\n",
+		"set-cookie: token=xyz\r\n",
+	} {
+		_, _, err := headers.Parse([]byte(line))
+		require.NoError(t, err)
+	}
+
+	assert.Equal(t, "session=abc; Expires=Wed, 21 Oct 2015 07:28:00 GMT", headers.Get("SET-COOKIE"))
+	assert.Equal(t, []string{"session=abc; Expires=Wed, 21 Oct 2015 07:28:00 GMT", "token=xyz"}, headers.Values("set-cookie"))
+	assert.Nil(t, headers.Values("missing"))
+	assert.True(t, headers.Has("x-trace"))
+	assert.Equal(t, 4, headers.Len())
+
+	var names []string
+	for name := range headers.All() {
+		names = append(names, name)
+	}
+	assert.Equal(t, []string{"Host", "Set-Cookie", "X-Trace", "set-cookie"}, names)
+
+	clone := headers.Clone()
+	clone.Add("X-Trace", "two")
+	clone.Del("set-cookie")
+	assert.Equal(t, []string{"one", "two"}, clone.Values("x-trace"))
+	assert.Nil(t, clone.Values("set-cookie"))
+	assert.Equal(t, []string{"one"}, headers.Values("x-trace"))
+	assert.Len(t, headers.Values("set-cookie"), 2)
+
+	headers.Set("SET-COOKIE", "replaced=1")
+	assert.Equal(t, []string{"replaced=1"}, headers.Values("set-cookie"))
+	names = nil
+	for name := range headers.All() {
+		names = append(names, name)
+	}
+	assert.Equal(t, []string{"Host", "SET-COOKIE", "X-Trace"}, names)
+
+	var nilHeaders *Headers
+	assert.Equal(t, "", nilHeaders.Get("host"))
+	assert.False(t, nilHeaders.HasToken("connection", "close"))
+	assert.Equal(t, 0, nilHeaders.Len())
+}
+
+func TestIsCookieValue(t *testing.T) {
+	for _, value := range []string{"", "abc123", "\"quoted\"", "\"\"", "a=b", "!#$%&'()*+-./:<=>?@[]^_`{|}~"} {
+		assert.True(t, IsCookieValue(value), value)
+	}
+
+	for _, value := range []string{"has space", "semi;colon", "com,ma", "back\\slash", "\"unbalanced", "in\"side", "\"a\"b\"", "tab\t", "\x7f", "é"} {
+		assert.False(t, IsCookieValue(value), value)
+	}
 }
@@ -0,0 +1,70 @@
+package request
+
+import "io"
+
+type body struct {
+	req    *Request
+	closed bool
+}
+
+func (b *body) Read(p []byte) (int, error) {
+	if b.closed {
+		return 0, ErrBodyReadAfterClose
+	}
+
+	r := b.req
+	for len(r.pending) == 0 {
+		if r.state == requestStateDone {
+			return 0, io.EOF
+		}
+
+		if err := r.readMore(); err != nil {
+			return 0, err
+		}
+	}
+
+	n := copy(p, r.pending)
+	r.pending = r.pending[n:]
+	return n, nil
+}
+
+func (b *body) Close() error {
+	if b.closed {
+		return nil
+	}
+	b.closed = true
+
+	r := b.req
+	drained := 0
+	for {
+		drained += len(r.pending)
+		r.pending = r.pending[:0]
+		if r.state == requestStateDone {
+			r.pending = nil
+			return nil
+		}
+
+		if drained+r.bodyRemaining > maxDrainBytes {
+			return ErrBodyNotDrained
+		}
+
+		if err := r.readMore(); err != nil {
+			return err
+		}
+	}
+}
+
+func (r *Request) BodyPending() bool {
+	return r.state != requestStateDone
+}
+
+func (r *Request) BodyDrainable() bool {
+	switch r.state {
+	case requestStateDone:
+		return true
+	case requestStateParsingBody:
+		return len(r.pending)+r.bodyRemaining <= maxDrainBytes
+	default:
+		return false
+	}
+}
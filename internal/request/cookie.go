@@ -0,0 +1,46 @@
+package request
+
+import (
+	"strings"
+
+	"surya.httpfromtcp/internal/headers"
+)
+
+type Cookie struct {
+	Name  string
+	Value string
+}
+
+func (r *Request) Cookies() []Cookie {
+	var cookies []Cookie
+
+	for _, line := range r.Headers.Values("cookie") {
+		for _, pair := range strings.Split(line, ";") {
+			name, value, found := strings.Cut(strings.TrimSpace(pair), "=")
+			if !found || !headers.IsToken(name) {
+				continue
+			}
+
+			if !headers.IsCookieValue(value) {
+				continue
+			}
+
+			if len(value) >= 2 && value[0] == '"' && value[len(value)-1] == '"' {
+				value = value[1 : len(value)-1]
+			}
+
+			cookies = append(cookies, Cookie{Name: name, Value: value})
+		}
+	}
+
+	return cookies
+}
+
+func (r *Request) Cookie(name string) (Cookie, error) {
+	for _, c := range r.Cookies() {
+		if c.Name == name {
+			return c, nil
+		}
+	}
+	return Cookie{}, ErrNoCookie
+}
@@ -0,0 +1,29 @@
+package request
+
+import (
+	"errors"
+
+	"surya.httpfromtcp/internal/headers"
+)
+
+var (
+	ErrBadRequestLine            = errors.New("invalid request line")
+	ErrUnsupportedVersion        = errors.New("unsupported HTTP version")
+	ErrBadRequestTarget          = errors.New("invalid request target")
+	ErrBadHeader                 = headers.ErrInvalidHeader
+	ErrBadContentLength          = errors.New("invalid content-length")
+	ErrBadChunk                  = errors.New("invalid chunk")
+	ErrBadTransferEncoding       = errors.New("invalid transfer-encoding")
+	ErrUnsupportedTransferCoding = errors.New("unsupported transfer coding")
+	ErrRequestLineTooLong        = errors.New("request line too long")
+	ErrHeaderTooLarge            = errors.New("request header fields too large")
+	ErrBodyTooLarge              = errors.New("request body too large")
+	ErrBodyReadAfterClose        = errors.New("read on closed request body")
+	ErrBodyNotDrained            = errors.New("unread request body too large to drain")
+	ErrNotMultipart              = errors.New("request content type is not multipart/form-data")
+	ErrBadBoundary               = errors.New("invalid multipart boundary")
+	ErrBadMultipart              = errors.New("invalid multipart body")
+	ErrFormTooLarge              = errors.New("form body too large")
+	ErrMissingFile               = errors.New("no such file in multipart form")
+	ErrNoCookie                  = errors.New("named cookie not present")
+)
@@ -0,0 +1,163 @@
+package request
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/url"
+	"strings"
+)
+
+const (
+	DefaultMaxFormMemory   = 32 << 20
+	maxURLEncodedFormBytes = 10 << 20
+	maxBoundaryLength      = 70
+)
+
+func (r *Request) ParseForm() error {
+	if r.postForm != nil {
+		return nil
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(r.Headers.Get("content-type"))
+	switch mediaType {
+	case "application/x-www-form-urlencoded":
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxURLEncodedFormBytes+1))
+		if err != nil {
+			return err
+		}
+
+		if len(body) > maxURLEncodedFormBytes {
+			return ErrFormTooLarge
+		}
+
+		values, err := url.ParseQuery(string(body))
+		if err != nil {
+			return fmt.Errorf("invalid urlencoded form: %w", err)
+		}
+
+		r.postForm = values
+		return nil
+
+	case "multipart/form-data":
+		return r.ParseMultipartForm(DefaultMaxFormMemory)
+
+	default:
+		r.postForm = url.Values{}
+		return nil
+	}
+}
+
+func (r *Request) ParseMultipartForm(maxMemory int64) error {
+	if r.multipartForm != nil {
+		return nil
+	}
+
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return err
+	}
+
+	form, err := reader.ReadForm(maxMemory)
+	if err != nil {
+		if errors.Is(err, multipart.ErrMessageTooLarge) {
+			return fmt.Errorf("%w: %w", ErrFormTooLarge, err)
+		}
+		return fmt.Errorf("%w: %w", ErrBadMultipart, err)
+	}
+
+	r.multipartForm = form
+	r.postForm = url.Values(form.Value)
+	return nil
+}
+
+func (r *Request) MultipartReader() (*multipart.Reader, error) {
+	mediaType, params, err := mime.ParseMediaType(r.Headers.Get("content-type"))
+	if err != nil || mediaType != "multipart/form-data" {
+		return nil, ErrNotMultipart
+	}
+
+	boundary, ok := params["boundary"]
+	if !ok {
+		return nil, fmt.Errorf("%w: missing boundary parameter", ErrBadBoundary)
+	}
+
+	if err := validateBoundary(boundary); err != nil {
+		return nil, err
+	}
+
+	return multipart.NewReader(r.Body, boundary), nil
+}
+
+func (r *Request) PostForm() url.Values {
+	return r.postForm
+}
+
+func (r *Request) MultipartForm() *multipart.Form {
+	return r.multipartForm
+}
+
+func (r *Request) FormValue(name string) string {
+	if err := r.ParseForm(); err == nil {
+		if values, ok := r.postForm[name]; ok && len(values) > 0 {
+			return values[0]
+		}
+	}
+
+	return r.Query().Get(name)
+}
+
+func (r *Request) FormFile(name string) (multipart.File, *multipart.FileHeader, error) {
+	if r.multipartForm == nil {
+		if err := r.ParseMultipartForm(DefaultMaxFormMemory); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	files := r.multipartForm.File[name]
+	if len(files) == 0 {
+		return nil, nil, ErrMissingFile
+	}
+
+	f, err := files[0].Open()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return f, files[0], nil
+}
+
+func (r *Request) RemoveTempFiles() error {
+	if r.multipartForm == nil {
+		return nil
+	}
+	return r.multipartForm.RemoveAll()
+}
+
+func validateBoundary(boundary string) error {
+	if boundary == "" || len(boundary) > maxBoundaryLength {
+		return fmt.Errorf("%w: length must be between 1 and %d", ErrBadBoundary, maxBoundaryLength)
+	}
+
+	if strings.HasSuffix(boundary, " ") {
+		return fmt.Errorf("%w: must not end with a space", ErrBadBoundary)
+	}
+
+	for i := 0; i < len(boundary); i++ {
+		c := boundary[i]
+		if !isBoundaryChar(c) {
+			return fmt.Errorf("%w: invalid character %q", ErrBadBoundary, c)
+		}
+	}
+
+	return nil
+}
+
+func isBoundaryChar(c byte) bool {
+	return (c >= 'a' && c <= 'z') ||
+		(c >= 'A' && c <= 'Z') ||
+		(c >= '0' && c <= '9') ||
+		strings.IndexByte("'()+_,-./:=? ", c) != -1
+}
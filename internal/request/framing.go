@@ -0,0 +1,83 @@
+package request
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"surya.httpfromtcp/internal/headers"
+)
+
+func bodyFraming(h *headers.Headers) (chunked bool, contentLength int, err error) {
+	transferEncodings := h.Values("transfer-encoding")
+	contentLengths := h.Values("content-length")
+
+	if len(transferEncodings) > 0 {
+		if len(contentLengths) > 0 {
+			return false, 0, fmt.Errorf("%w: request has both transfer-encoding and content-length", ErrBadTransferEncoding)
+		}
+
+		if err := validateTransferCodings(transferEncodings); err != nil {
+			return false, 0, err
+		}
+
+		return true, 0, nil
+	}
+
+	if len(contentLengths) == 0 {
+		return false, -1, nil
+	}
+
+	contentLength, err = parseContentLength(contentLengths)
+	if err != nil {
+		return false, 0, err
+	}
+
+	return false, contentLength, nil
+}
+
+func validateTransferCodings(values []string) error {
+	var codings []string
+	for _, value := range values {
+		for _, coding := range strings.Split(value, ",") {
+			coding = strings.TrimSpace(coding)
+			if coding == "" {
+				continue
+			}
+
+			name, _, _ := strings.Cut(coding, ";")
+			name = strings.ToLower(strings.TrimSpace(name))
+			if name != "chunked" {
+				return fmt.Errorf("%w: %q", ErrUnsupportedTransferCoding, name)
+			}
+
+			codings = append(codings, name)
+		}
+	}
+
+	if len(codings) != 1 {
+		return fmt.Errorf("%w: chunked must be applied exactly once", ErrBadTransferEncoding)
+	}
+
+	return nil
+}
+
+func parseContentLength(values []string) (int, error) {
+	contentLength := -1
+	for _, value := range values {
+		for _, part := range strings.Split(value, ",") {
+			part = strings.TrimSpace(part)
+			n, err := strconv.Atoi(part)
+			if err != nil || strings.Trim(part, "0123456789") != "" {
+				return 0, fmt.Errorf("%w: %q", ErrBadContentLength, value)
+			}
+
+			if contentLength != -1 && n != contentLength {
+				return 0, fmt.Errorf("%w: conflicting values %d and %d", ErrBadContentLength, contentLength, n)
+			}
+			contentLength = n
+		}
+	}
+
+	return contentLength, nil
+}
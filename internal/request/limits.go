@@ -0,0 +1,28 @@
+package request
+
+const (
+	maxChunkSizeLineBytes = 4096
+	minBufferSize         = 4096
+	maxDrainBytes         = 256 << 10
+)
+
+type Limits struct {
+	MaxRequestLineBytes int
+	MaxHeaderCount      int
+	MaxHeaderBytes      int
+	MaxBodyBytes        int
+}
+
+var DefaultLimits = Limits{
+	MaxRequestLineBytes: 8 << 10,
+	MaxHeaderCount:      100,
+	MaxHeaderBytes:      64 << 10,
+}
+
+func exceeds(size, limit int) bool {
+	return limit > 0 && size > limit
+}
+
+func (l Limits) BufferSize() int {
+	return max(minBufferSize, l.MaxRequestLineBytes+2, l.MaxHeaderBytes+2)
+}
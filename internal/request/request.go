@@ -1,11 +1,13 @@
 package request
 
 import (
+	"bufio"
 	"bytes"
 	"errors"
 	"fmt"
 	"io"
-	"strconv"
+	"mime/multipart"
+	"net/url"
 	"strings"
 	"unicode"
 
@@ -16,14 +18,32 @@ const (
 	requestStateInitialized = iota
 	requestStateParsingHeaders
 	requestStateParsingBody
+	requestStateParsingChunkSize
+	requestStateParsingChunkData
+	requestStateParsingChunkDataEnd
+	requestStateParsingTrailers
 	requestStateDone
 )
 
 type Request struct {
-	RequestLine RequestLine
-	Headers     headers.Headers
-	Body        []byte
-	state       int
+	RequestLine    RequestLine
+	Headers        *headers.Headers
+	Body           io.ReadCloser
+	Trailers       *headers.Headers
+	target         target
+	pathValues     map[string]string
+	postForm       url.Values
+	multipartForm  *multipart.Form
+	state          int
+	limits         Limits
+	headerCount    int
+	headerBytes    int
+	bodyBytes      int
+	bodyRemaining  int
+	chunkRemaining int
+	reader         *bufio.Reader
+	needMore       bool
+	pending        []byte
 }
 
 type RequestLine struct {
@@ -33,40 +53,99 @@ type RequestLine struct {
 }
 
 func RequestFromReader(reader io.Reader) (*Request, error) {
+	return RequestFromReaderWithLimits(reader, DefaultLimits)
+}
+
+// Bytes past the end of the request stay buffered only when reader is a
+// *bufio.Reader; pass the same one in again to parse the next request.
+func RequestFromReaderWithLimits(reader io.Reader, limits Limits) (*Request, error) {
+	br, ok := reader.(*bufio.Reader)
+	if !ok {
+		br = bufio.NewReaderSize(reader, limits.BufferSize())
+	}
+
 	req := &Request{
 		state:   requestStateInitialized,
 		Headers: headers.NewHeaders(),
+		limits:  limits,
+		reader:  br,
 	}
+	req.Body = &body{req: req}
 
-	buffer := make([]byte, 8)
-	var accumulated []byte
+	for req.state < requestStateParsingBody {
+		if err := req.readMore(); err != nil {
+			return nil, err
+		}
+	}
 
-	for req.state != requestStateDone {
-		n, err := reader.Read(buffer)
-		if n > 0 {
-			accumulated = append(accumulated, buffer[:n]...)
+	return req, nil
+}
 
-			consumed, parseErr := req.parse(accumulated)
-			if parseErr != nil {
-				return nil, parseErr
-			}
+func (r *Request) ReadBody() ([]byte, error) {
+	return io.ReadAll(r.Body)
+}
 
-			accumulated = accumulated[consumed:]
-		}
+func (r *Request) KeepAlive() bool {
+	if r.RequestLine.HttpVersion == "1.0" {
+		return r.Headers.HasToken("connection", "keep-alive") && !r.Headers.HasToken("connection", "close")
+	}
+	return !r.Headers.HasToken("connection", "close")
+}
 
-		if err == io.EOF {
-			if req.state != requestStateDone {
-				return nil, errors.New("incomplete request")
-			}
-			break
-		}
+func (r *Request) PathValue(name string) string {
+	return r.pathValues[name]
+}
 
-		if err != nil {
-			return nil, err
+func (r *Request) SetPathValue(name, value string) {
+	if r.pathValues == nil {
+		r.pathValues = make(map[string]string)
+	}
+	r.pathValues[name] = value
+}
+
+func (r *Request) readMore() error {
+	n := r.reader.Buffered()
+	if n == 0 || r.needMore {
+		n++
+	}
+
+	data, err := r.reader.Peek(n)
+
+	consumed, parseErr := r.parse(data)
+	if parseErr != nil {
+		return parseErr
+	}
+
+	r.reader.Discard(consumed)
+	r.needMore = consumed == 0
+
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, bufio.ErrBufferFull):
+		if consumed > 0 {
+			return nil
+		}
+		return r.lineTooLong()
+	case err == io.EOF:
+		if r.state != requestStateDone {
+			return fmt.Errorf("incomplete request: %w", io.ErrUnexpectedEOF)
 		}
+		return nil
+	default:
+		return err
 	}
+}
 
-	return req, nil
+func (r *Request) lineTooLong() error {
+	switch r.state {
+	case requestStateInitialized:
+		return ErrRequestLineTooLong
+	case requestStateParsingHeaders, requestStateParsingTrailers:
+		return ErrHeaderTooLarge
+	default:
+		return fmt.Errorf("%w: size line too long", ErrBadChunk)
+	}
 }
 
 func (r *Request) parse(data []byte) (int, error) {
@@ -100,55 +179,226 @@ func (r *Request) parseSingle(data []byte) (int, error) {
 		}
 
 		if consumed == 0 {
+			if exceeds(len(data), r.limits.MaxRequestLineBytes) {
+				return 0, ErrRequestLineTooLong
+			}
 			return 0, nil
 		}
 
+		if exceeds(consumed-2, r.limits.MaxRequestLineBytes) {
+			return 0, ErrRequestLineTooLong
+		}
+
+		target, err := parseTarget(requestLine.Method, requestLine.RequestTarget)
+		if err != nil {
+			return 0, err
+		}
+
 		r.RequestLine = *requestLine
+		r.target = target
 		r.state = requestStateParsingHeaders
 
 		return consumed, nil
 
 	case requestStateParsingHeaders:
-		n, done, err := r.Headers.Parse(data)
+		n, done, err := r.parseField(r.Headers, data)
 		if err != nil {
 			return 0, err
 		}
 
 		if done {
-			r.state = requestStateParsingBody
+			if err := r.startBody(); err != nil {
+				return 0, err
+			}
 		}
 
 		return n, nil
 
 	case requestStateParsingBody:
-		contentLengthStr := r.Headers.Get("content-length")
-		if contentLengthStr == "" {
+		n := min(len(data), r.bodyRemaining)
+		r.pending = append(r.pending, data[:n]...)
+		r.bodyRemaining -= n
+
+		if r.bodyRemaining == 0 {
 			r.state = requestStateDone
-			return 0, nil
 		}
 
-		contentLength, err := strconv.Atoi(contentLengthStr)
+		return n, nil
+
+	case requestStateParsingChunkSize:
+		consumed, size, err := parseChunkSize(data)
 		if err != nil {
-			return 0, fmt.Errorf("invalid content-length: %w", err)
+			return 0, err
 		}
 
-		r.Body = append(r.Body, data...)
+		if consumed == 0 {
+			return 0, nil
+		}
 
-		if len(r.Body) > contentLength {
-			return 0, fmt.Errorf("body length exceeds content-length: got %d, expected %d", len(r.Body), contentLength)
+		r.bodyBytes += size
+		if exceeds(r.bodyBytes, r.limits.MaxBodyBytes) {
+			return 0, ErrBodyTooLarge
 		}
 
-		if len(r.Body) == contentLength {
+		if size == 0 {
+			r.Trailers = headers.NewHeaders()
+			r.state = requestStateParsingTrailers
+		} else {
+			r.chunkRemaining = size
+			r.state = requestStateParsingChunkData
+		}
+
+		return consumed, nil
+
+	case requestStateParsingChunkData:
+		n := min(len(data), r.chunkRemaining)
+		r.pending = append(r.pending, data[:n]...)
+		r.chunkRemaining -= n
+
+		if r.chunkRemaining == 0 {
+			r.state = requestStateParsingChunkDataEnd
+		}
+
+		return n, nil
+
+	case requestStateParsingChunkDataEnd:
+		if len(data) < 2 {
+			return 0, nil
+		}
+
+		if !bytes.HasPrefix(data, []byte("\r\n")) {
+			return 0, fmt.Errorf("%w: data not followed by CRLF", ErrBadChunk)
+		}
+
+		r.state = requestStateParsingChunkSize
+		return 2, nil
+
+	case requestStateParsingTrailers:
+		n, done, err := r.parseField(r.Trailers, data)
+		if err != nil {
+			return 0, fmt.Errorf("invalid trailer: %w", err)
+		}
+
+		if done {
 			r.state = requestStateDone
 		}
 
-		return len(data), nil
+		return n, nil
 
 	default:
 		return 0, nil
 	}
 }
 
+func (r *Request) parseField(h *headers.Headers, data []byte) (int, bool, error) {
+	n, done, err := h.Parse(data)
+	if err != nil {
+		return 0, false, err
+	}
+
+	if n == 0 {
+		if exceeds(r.headerBytes+len(data), r.limits.MaxHeaderBytes) {
+			return 0, false, ErrHeaderTooLarge
+		}
+		return 0, false, nil
+	}
+
+	r.headerBytes += n
+	if exceeds(r.headerBytes, r.limits.MaxHeaderBytes) {
+		return 0, false, ErrHeaderTooLarge
+	}
+
+	if !done {
+		r.headerCount++
+		if exceeds(r.headerCount, r.limits.MaxHeaderCount) {
+			return 0, false, ErrHeaderTooLarge
+		}
+	}
+
+	return n, done, nil
+}
+
+func (r *Request) startBody() error {
+	if r.RequestLine.HttpVersion == "1.0" && r.Headers.Has("transfer-encoding") {
+		return fmt.Errorf("%w: transfer-encoding in an HTTP/1.0 request", ErrBadTransferEncoding)
+	}
+
+	chunked, contentLength, err := bodyFraming(r.Headers)
+	if err != nil {
+		return err
+	}
+
+	if chunked {
+		r.state = requestStateParsingChunkSize
+		return nil
+	}
+
+	if exceeds(contentLength, r.limits.MaxBodyBytes) {
+		return ErrBodyTooLarge
+	}
+
+	if contentLength <= 0 {
+		r.state = requestStateDone
+		return nil
+	}
+
+	r.bodyRemaining = contentLength
+	r.state = requestStateParsingBody
+	return nil
+}
+
+func parseChunkSize(data []byte) (int, int, error) {
+	idx := bytes.Index(data, []byte("\r\n"))
+	if idx == -1 {
+		if len(data) > maxChunkSizeLineBytes {
+			return 0, 0, fmt.Errorf("%w: size line too long", ErrBadChunk)
+		}
+		return 0, 0, nil
+	}
+
+	line := string(data[:idx])
+	for i := 0; i < len(line); i++ {
+		if c := line[i]; c != '\t' && (c < ' ' || c == 0x7f) {
+			return 0, 0, fmt.Errorf("%w: control character in size line", ErrBadChunk)
+		}
+	}
+
+	sizeStr, _, _ := strings.Cut(line, ";")
+	sizeStr = strings.TrimRight(sizeStr, " \t")
+
+	if sizeStr == "" || len(sizeStr) > 15 {
+		return 0, 0, fmt.Errorf("%w: bad size %q", ErrBadChunk, sizeStr)
+	}
+
+	size := 0
+	for i := 0; i < len(sizeStr); i++ {
+		digit, ok := hexDigit(sizeStr[i])
+		if !ok {
+			return 0, 0, fmt.Errorf("%w: bad size %q", ErrBadChunk, sizeStr)
+		}
+		size = size<<4 | digit
+	}
+
+	return idx + 2, size, nil
+}
+
+func hexDigit(c byte) (int, bool) {
+	switch {
+	case c >= '0' && c <= '9':
+		return int(c - '0'), true
+	case c >= 'a' && c <= 'f':
+		return int(c-'a') + 10, true
+	case c >= 'A' && c <= 'F':
+		return int(c-'A') + 10, true
+	default:
+		return 0, false
+	}
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
 func parseRequestLine(data []byte) (int, *RequestLine, error) {
 	idx := bytes.Index(data, []byte("\r\n"))
 	if idx == -1 {
@@ -159,7 +409,7 @@ func parseRequestLine(data []byte) (int, *RequestLine, error) {
 
 	parts := strings.Split(firstLine, " ")
 	if len(parts) != 3 {
-		return 0, nil, errors.New("invalid request line: expected 3 parts")
+		return 0, nil, fmt.Errorf("%w: expected 3 parts", ErrBadRequestLine)
 	}
 
 	method := parts[0]
@@ -168,17 +418,21 @@ func parseRequestLine(data []byte) (int, *RequestLine, error) {
 
 	for _, ch := range method {
 		if !unicode.IsUpper(ch) {
-			return 0, nil, errors.New("invalid method: must contain only capital letters")
+			return 0, nil, fmt.Errorf("%w: method must contain only capital letters", ErrBadRequestLine)
 		}
 	}
 
 	if !strings.HasPrefix(httpVersionFull, "HTTP/") {
-		return 0, nil, errors.New("invalid HTTP version format")
+		return 0, nil, fmt.Errorf("%w: malformed HTTP version", ErrBadRequestLine)
 	}
 
 	version := strings.TrimPrefix(httpVersionFull, "HTTP/")
-	if version != "1.1" {
-		return 0, nil, errors.New("unsupported HTTP version: only HTTP/1.1 is supported")
+	if len(version) != 3 || !isDigit(version[0]) || version[1] != '.' || !isDigit(version[2]) {
+		return 0, nil, fmt.Errorf("%w: malformed HTTP version", ErrBadRequestLine)
+	}
+
+	if version[0] != '1' {
+		return 0, nil, fmt.Errorf("%w: only HTTP/1.x is supported", ErrUnsupportedVersion)
 	}
 
 	consumed := idx + 2
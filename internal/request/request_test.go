@@ -1,7 +1,9 @@
 package request
 
 import (
+	"bufio"
 	"io"
+	"strconv"
 	"strings"
 	"testing"
 
@@ -29,6 +31,20 @@ func (cr *chunkReader) Read(p []byte) (n int, err error) {
 	return n, nil
 }
 
+func readFullRequest(reader io.Reader) (*Request, []byte, error) {
+	r, err := RequestFromReader(reader)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	body, err := r.ReadBody()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return r, body, nil
+}
+
 func TestRequestLineParse(t *testing.T) {
 	reader := &chunkReader{
 		data:            "GET / HTTP/1.1\r\nHost: localhost:42069\r\nUser-Agent: curl/7.81.0\r\nAccept: */*\r\n\r\n",
@@ -65,12 +81,15 @@ func TestRequestLineParse(t *testing.T) {
 
 	_, err = RequestFromReader(strings.NewReader("/coffee HTTP/1.1\r\nHost: localhost:42069\r\nUser-Agent: curl/7.81.0\r\nAccept: */*\r\n\r\n"))
 	require.Error(t, err)
+	assert.ErrorIs(t, err, ErrBadRequestLine)
 
 	_, err = RequestFromReader(strings.NewReader("Get /coffee HTTP/1.1\r\nHost: localhost:42069\r\nUser-Agent: curl/7.81.0\r\nAccept: */*\r\n\r\n"))
 	require.Error(t, err)
+	assert.ErrorIs(t, err, ErrBadRequestLine)
 
 	_, err = RequestFromReader(strings.NewReader("GET /coffee HTTP/2.0\r\nHost: localhost:42069\r\nUser-Agent: curl/7.81.0\r\nAccept: */*\r\n\r\n"))
 	require.Error(t, err)
+	assert.ErrorIs(t, err, ErrUnsupportedVersion)
 
 	reader = &chunkReader{
 		data:            "GET /test HTTP/1.1\r\nHost: localhost:42069\r\nUser-Agent: curl/7.81.0\r\nAccept: */*\r\n\r\n",
@@ -92,9 +111,9 @@ func TestHeadersParse(t *testing.T) {
 	r, err := RequestFromReader(reader)
 	require.NoError(t, err)
 	require.NotNil(t, r)
-	assert.Equal(t, "localhost:42069", r.Headers["host"])
-	assert.Equal(t, "curl/7.81.0", r.Headers["user-agent"])
-	assert.Equal(t, "*/*", r.Headers["accept"])
+	assert.Equal(t, "localhost:42069", r.Headers.Get("host"))
+	assert.Equal(t, "curl/7.81.0", r.Headers.Get("user-agent"))
+	assert.Equal(t, "*/*", r.Headers.Get("accept"))
 
 	reader = &chunkReader{
 		data:            "GET / HTTP/1.1\r\n\r\n",
@@ -111,6 +130,7 @@ func TestHeadersParse(t *testing.T) {
 	}
 	r, err = RequestFromReader(reader)
 	require.Error(t, err)
+	assert.ErrorIs(t, err, ErrBadHeader)
 
 	reader = &chunkReader{
 		data:            "GET / HTTP/1.1\r\nSet-Cookie: session=abc\r\nSet-Cookie: token=xyz\r\n\r\n",
@@ -119,7 +139,7 @@ func TestHeadersParse(t *testing.T) {
 	r, err = RequestFromReader(reader)
 	require.NoError(t, err)
 	require.NotNil(t, r)
-	assert.Equal(t, "session=abc, token=xyz", r.Headers["set-cookie"])
+	assert.Equal(t, []string{"session=abc", "token=xyz"}, r.Headers.Values("set-cookie"))
 
 	reader = &chunkReader{
 		data:            "GET / HTTP/1.1\r\nContent-Type: application/json\r\nContent-Length: 0\r\n\r\n",
@@ -128,8 +148,8 @@ func TestHeadersParse(t *testing.T) {
 	r, err = RequestFromReader(reader)
 	require.NoError(t, err)
 	require.NotNil(t, r)
-	assert.Equal(t, "application/json", r.Headers["content-type"])
-	assert.Equal(t, "0", r.Headers["content-length"])
+	assert.Equal(t, "application/json", r.Headers.Get("content-type"))
+	assert.Equal(t, "0", r.Headers.Get("content-length"))
 
 	reader = &chunkReader{
 		data:            "GET / HTTP/1.1\r\nHost: localhost:42069\r\n",
@@ -152,7 +172,9 @@ func TestBodyParse(t *testing.T) {
 	r, err := RequestFromReader(reader)
 	require.NoError(t, err)
 	require.NotNil(t, r)
-	assert.Equal(t, "hello world!\n", string(r.Body))
+	body, err := r.ReadBody()
+	require.NoError(t, err)
+	assert.Equal(t, "hello world!\n", string(body))
 
 	reader = &chunkReader{
 		data: "POST /submit HTTP/1.1\r\n" +
@@ -164,7 +186,9 @@ func TestBodyParse(t *testing.T) {
 	r, err = RequestFromReader(reader)
 	require.NoError(t, err)
 	require.NotNil(t, r)
-	assert.Empty(t, r.Body)
+	body, err = r.ReadBody()
+	require.NoError(t, err)
+	assert.Empty(t, body)
 
 	reader = &chunkReader{
 		data: "POST /submit HTTP/1.1\r\n" +
@@ -175,7 +199,9 @@ func TestBodyParse(t *testing.T) {
 	r, err = RequestFromReader(reader)
 	require.NoError(t, err)
 	require.NotNil(t, r)
-	assert.Empty(t, r.Body)
+	body, err = r.ReadBody()
+	require.NoError(t, err)
+	assert.Empty(t, body)
 
 	reader = &chunkReader{
 		data: "POST /submit HTTP/1.1\r\n" +
@@ -186,7 +212,10 @@ func TestBodyParse(t *testing.T) {
 		numBytesPerRead: 3,
 	}
 	r, err = RequestFromReader(reader)
+	require.NoError(t, err)
+	_, err = r.ReadBody()
 	require.Error(t, err)
+	assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
 
 	reader = &chunkReader{
 		data: "POST /submit HTTP/1.1\r\n" +
@@ -198,5 +227,602 @@ func TestBodyParse(t *testing.T) {
 	r, err = RequestFromReader(reader)
 	require.NoError(t, err)
 	require.NotNil(t, r)
-	assert.Empty(t, r.Body)
+	body, err = r.ReadBody()
+	require.NoError(t, err)
+	assert.Empty(t, body)
+}
+
+func TestChunkedBodyParse(t *testing.T) {
+	reader := &chunkReader{
+		data: "POST /submit HTTP/1.1\r\n" +
+			"Host: localhost:42069\r\n" +
+			"Transfer-Encoding: chunked\r\n" +
+			"\r\n" +
+			"6\r\n" +
+			"hello \r\n" +
+			"7;name=value\r\n" +
+			"world!\n\r\n" +
+			"0\r\n" +
+			"\r\n",
+		numBytesPerRead: 3,
+	}
+	r, err := RequestFromReader(reader)
+	require.NoError(t, err)
+	require.NotNil(t, r)
+	body, err := r.ReadBody()
+	require.NoError(t, err)
+	assert.Equal(t, "hello world!\n", string(body))
+	assert.Empty(t, r.Trailers)
+
+	reader = &chunkReader{
+		data: "POST /submit HTTP/1.1\r\n" +
+			"Host: localhost:42069\r\n" +
+			"Transfer-Encoding: chunked\r\n" +
+			"\r\n" +
+			"1A\r\n" +
+			"abcdefghijklmnopqrstuvwxyz\r\n" +
+			"0\r\n" +
+			"X-Checksum: 1234\r\n" +
+			"\r\n",
+		numBytesPerRead: 1,
+	}
+	r, err = RequestFromReader(reader)
+	require.NoError(t, err)
+	require.NotNil(t, r)
+	body, err = r.ReadBody()
+	require.NoError(t, err)
+	assert.Equal(t, "abcdefghijklmnopqrstuvwxyz", string(body))
+	assert.Equal(t, "1234", r.Trailers.Get("x-checksum"))
+
+	reader = &chunkReader{
+		data: "POST /submit HTTP/1.1\r\n" +
+			"Host: localhost:42069\r\n" +
+			"Transfer-Encoding: chunked\r\n" +
+			"\r\n" +
+			"0\r\n" +
+			"\r\n",
+		numBytesPerRead: 4,
+	}
+	r, err = RequestFromReader(reader)
+	require.NoError(t, err)
+	require.NotNil(t, r)
+	body, err = r.ReadBody()
+	require.NoError(t, err)
+	assert.Empty(t, body)
+
+	reader = &chunkReader{
+		data: "POST /submit HTTP/1.1\r\n" +
+			"Host: localhost:42069\r\n" +
+			"Transfer-Encoding: chunked\r\n" +
+			"\r\n" +
+			"zz\r\n" +
+			"hello\r\n" +
+			"0\r\n" +
+			"\r\n",
+		numBytesPerRead: 8,
+	}
+	_, _, err = readFullRequest(reader)
+	require.Error(t, err)
+
+	reader = &chunkReader{
+		data: "POST /submit HTTP/1.1\r\n" +
+			"Host: localhost:42069\r\n" +
+			"Transfer-Encoding: chunked\r\n" +
+			"\r\n" +
+			"3\r\n" +
+			"hello\r\n" +
+			"0\r\n" +
+			"\r\n",
+		numBytesPerRead: 8,
+	}
+	_, _, err = readFullRequest(reader)
+	require.Error(t, err)
+
+	reader = &chunkReader{
+		data: "POST /submit HTTP/1.1\r\n" +
+			"Host: localhost:42069\r\n" +
+			"Transfer-Encoding: chunked\r\n" +
+			"\r\n" +
+			"5\r\n" +
+			"hello\r\n",
+		numBytesPerRead: 8,
+	}
+	_, _, err = readFullRequest(reader)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "incomplete")
+}
+
+func TestBodyStreaming(t *testing.T) {
+	reader := &chunkReader{
+		data: "POST /upload HTTP/1.1\r\n" +
+			"Host: localhost:42069\r\n" +
+			"Content-Length: 26\r\n" +
+			"\r\n" +
+			"abcdefghijklmnopqrstuvwxyz",
+		numBytesPerRead: 4,
+	}
+	r, err := RequestFromReader(reader)
+	require.NoError(t, err)
+	require.NotNil(t, r)
+	assert.Less(t, reader.pos, len(reader.data))
+
+	buf := make([]byte, 5)
+	n, err := io.ReadFull(r.Body, buf)
+	require.NoError(t, err)
+	assert.Equal(t, "abcde", string(buf[:n]))
+	assert.Less(t, reader.pos, len(reader.data))
+
+	rest, err := io.ReadAll(r.Body)
+	require.NoError(t, err)
+	assert.Equal(t, "fghijklmnopqrstuvwxyz", string(rest))
+
+	reader = &chunkReader{
+		data: "POST /upload HTTP/1.1\r\n" +
+			"Host: localhost:42069\r\n" +
+			"Transfer-Encoding: chunked\r\n" +
+			"\r\n" +
+			"5\r\nhello\r\n" +
+			"6\r\n world\r\n" +
+			"0\r\n\r\n",
+		numBytesPerRead: 3,
+	}
+	r, err = RequestFromReader(reader)
+	require.NoError(t, err)
+	require.NoError(t, r.Body.Close())
+	assert.Equal(t, len(reader.data), reader.pos)
+
+	_, err = r.Body.Read(buf)
+	assert.ErrorIs(t, err, ErrBodyReadAfterClose)
+
+	large := strings.Repeat("x", maxDrainBytes+1)
+	r, err = RequestFromReader(strings.NewReader("POST / HTTP/1.1\r\nContent-Length: " + strconv.Itoa(len(large)) + "\r\n\r\n" + large))
+	require.NoError(t, err)
+	assert.False(t, r.BodyDrainable())
+	assert.ErrorIs(t, r.Body.Close(), ErrBodyNotDrained)
+
+	chunk := strings.Repeat("x", 64<<10)
+	chunked := "POST / HTTP/1.1\r\nTransfer-Encoding: chunked\r\n\r\n" + strings.Repeat("10000\r\n"+chunk+"\r\n", 5) + "0\r\n\r\n"
+	r, err = RequestFromReader(strings.NewReader(chunked))
+	require.NoError(t, err)
+	assert.False(t, r.BodyDrainable())
+	assert.ErrorIs(t, r.Body.Close(), ErrBodyNotDrained)
+}
+
+func TestLimits(t *testing.T) {
+	limits := Limits{
+		MaxRequestLineBytes: 32,
+		MaxHeaderCount:      2,
+		MaxHeaderBytes:      64,
+		MaxBodyBytes:        10,
+	}
+
+	_, err := RequestFromReaderWithLimits(strings.NewReader("GET /"+strings.Repeat("a", 40)+" HTTP/1.1\r\n\r\n"), limits)
+	assert.ErrorIs(t, err, ErrRequestLineTooLong)
+
+	_, err = RequestFromReaderWithLimits(&chunkReader{data: "GET /" + strings.Repeat("a", 100), numBytesPerRead: 3}, limits)
+	assert.ErrorIs(t, err, ErrRequestLineTooLong)
+
+	_, err = RequestFromReaderWithLimits(strings.NewReader("GET / HTTP/1.1\r\nA: 1\r\nB: 2\r\nC: 3\r\n\r\n"), limits)
+	assert.ErrorIs(t, err, ErrHeaderTooLarge)
+
+	_, err = RequestFromReaderWithLimits(strings.NewReader("GET / HTTP/1.1\r\nA: "+strings.Repeat("x", 70)+"\r\n\r\n"), limits)
+	assert.ErrorIs(t, err, ErrHeaderTooLarge)
+
+	_, err = RequestFromReaderWithLimits(&chunkReader{data: "GET / HTTP/1.1\r\nA: " + strings.Repeat("x", 100), numBytesPerRead: 3}, limits)
+	assert.ErrorIs(t, err, ErrHeaderTooLarge)
+
+	_, err = RequestFromReaderWithLimits(strings.NewReader("POST / HTTP/1.1\r\nContent-Length: 11\r\n\r\nhello world"), limits)
+	assert.ErrorIs(t, err, ErrBodyTooLarge)
+
+	_, _, err = readFullRequest(&chunkReader{
+		data:            "POST / HTTP/1.1\r\nTransfer-Encoding: chunked\r\n\r\n6\r\nhello \r\n6\r\nworld!\r\n0\r\n\r\n",
+		numBytesPerRead: 3,
+	})
+	require.NoError(t, err)
+
+	r, err := RequestFromReaderWithLimits(&chunkReader{
+		data:            "POST / HTTP/1.1\r\nTransfer-Encoding: chunked\r\n\r\n6\r\nhello \r\n6\r\nworld!\r\n0\r\n\r\n",
+		numBytesPerRead: 3,
+	}, limits)
+	if err == nil {
+		_, err = r.ReadBody()
+	}
+	assert.ErrorIs(t, err, ErrBodyTooLarge)
+
+	r, err = RequestFromReaderWithLimits(strings.NewReader("POST / HTTP/1.1\r\nA: 1\r\nContent-Length: 10\r\n\r\n0123456789"), limits)
+	require.NoError(t, err)
+	body, err := r.ReadBody()
+	require.NoError(t, err)
+	assert.Equal(t, "0123456789", string(body))
+}
+
+func TestParseErrors(t *testing.T) {
+	_, err := RequestFromReader(strings.NewReader("POST / HTTP/1.1\r\nContent-Length: abc\r\n\r\n"))
+	assert.ErrorIs(t, err, ErrBadContentLength)
+
+	_, err = RequestFromReader(strings.NewReader("POST / HTTP/1.1\r\nContent-Length: -3\r\n\r\nabc"))
+	assert.ErrorIs(t, err, ErrBadContentLength)
+
+	_, _, err = readFullRequest(strings.NewReader("POST / HTTP/1.1\r\nTransfer-Encoding: chunked\r\n\r\nxyz\r\n"))
+	assert.ErrorIs(t, err, ErrBadChunk)
+
+	_, _, err = readFullRequest(strings.NewReader("POST / HTTP/1.1\r\nTransfer-Encoding: chunked\r\n\r\n0\r\nbad trailer\r\n\r\n"))
+	assert.ErrorIs(t, err, ErrBadHeader)
+}
+
+func TestRequestSmuggling(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers string
+		body    string
+		err     error
+	}{
+		{
+			name:    "CL.TE",
+			headers: "Content-Length: 13\r\nTransfer-Encoding: chunked\r\n",
+			body:    "0\r\n\r\nSMUGGLED",
+			err:     ErrBadTransferEncoding,
+		},
+		{
+			name:    "TE.CL",
+			headers: "Transfer-Encoding: chunked\r\nContent-Length: 3\r\n",
+			body:    "8\r\nSMUGGLED\r\n0\r\n\r\n",
+			err:     ErrBadTransferEncoding,
+		},
+		{
+			name:    "obfuscated TE",
+			headers: "Transfer-Encoding: xchunked\r\n",
+			body:    "0\r\n\r\n",
+			err:     ErrUnsupportedTransferCoding,
+		},
+		{
+			name:    "TE with trailing identity",
+			headers: "Transfer-Encoding: chunked\r\nTransfer-Encoding: identity\r\n",
+			body:    "0\r\n\r\n",
+			err:     ErrUnsupportedTransferCoding,
+		},
+		{
+			name:    "TE chunked twice",
+			headers: "Transfer-Encoding: chunked, chunked\r\n",
+			body:    "0\r\n\r\n",
+			err:     ErrBadTransferEncoding,
+		},
+		{
+			name:    "differing duplicate CL",
+			headers: "Content-Length: 5\r\nContent-Length: 6\r\n",
+			body:    "hello!",
+			err:     ErrBadContentLength,
+		},
+		{
+			name:    "differing CL list",
+			headers: "Content-Length: 5, 6\r\n",
+			body:    "hello!",
+			err:     ErrBadContentLength,
+		},
+		{
+			name:    "negative CL",
+			headers: "Content-Length: -5\r\n",
+			body:    "hello",
+			err:     ErrBadContentLength,
+		},
+		{
+			name:    "signed CL",
+			headers: "Content-Length: +5\r\n",
+			body:    "hello",
+			err:     ErrBadContentLength,
+		},
+		{
+			name:    "hex CL",
+			headers: "Content-Length: 0x5\r\n",
+			body:    "hello",
+			err:     ErrBadContentLength,
+		},
+		{
+			name:    "empty CL",
+			headers: "Content-Length: \r\n",
+			body:    "hello",
+			err:     ErrBadContentLength,
+		},
+		{
+			name:    "overflowing CL",
+			headers: "Content-Length: 99999999999999999999999\r\n",
+			body:    "hello",
+			err:     ErrBadContentLength,
+		},
+		{
+			name:    "bare LF in chunk extension",
+			headers: "Transfer-Encoding: chunked\r\n",
+			body:    "5;a\nX\r\nhello\r\n0\r\n\r\n",
+			err:     ErrBadChunk,
+		},
+		{
+			name:    "bare CR in chunk extension",
+			headers: "Transfer-Encoding: chunked\r\n",
+			body:    "5;a\rX\r\nhello\r\n0\r\n\r\n",
+			err:     ErrBadChunk,
+		},
+		{
+			name:    "NUL in chunk size line",
+			headers: "Transfer-Encoding: chunked\r\n",
+			body:    "5\x00\r\nhello\r\n0\r\n\r\n",
+			err:     ErrBadChunk,
+		},
+		{
+			name:    "obs-fold TE",
+			headers: "X-Foo: bar\r\n Transfer-Encoding: chunked\r\n",
+			body:    "0\r\n\r\n",
+			err:     ErrBadHeader,
+		},
+		{
+			name:    "obs-fold TE with tab",
+			headers: "X-Foo: bar\r\n\tTransfer-Encoding: chunked\r\n",
+			body:    "0\r\n\r\n",
+			err:     ErrBadHeader,
+		},
+	}
+
+	for _, tt := range tests {
+		_, _, err := readFullRequest(strings.NewReader("POST / HTTP/1.1\r\nHost: localhost\r\n" + tt.headers + "\r\n" + tt.body))
+		assert.ErrorIs(t, err, tt.err, tt.name)
+	}
+
+	_, _, err := readFullRequest(strings.NewReader("GET / HTTP/1.1\r\n Host: x\r\n\r\n"))
+	assert.ErrorIs(t, err, ErrBadHeader)
+
+	r, body, err := readFullRequest(strings.NewReader("POST / HTTP/1.1\r\nContent-Length: 5\r\nContent-Length: 5\r\n\r\nhello"))
+	require.NoError(t, err)
+	require.NotNil(t, r)
+	assert.Equal(t, "hello", string(body))
+
+	_, body, err = readFullRequest(strings.NewReader("POST / HTTP/1.1\r\nContent-Length: 5, 5\r\n\r\nhello"))
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(body))
+
+	_, body, err = readFullRequest(strings.NewReader("POST / HTTP/1.1\r\nTransfer-Encoding: Chunked\r\n\r\n5\r\nhello\r\n0\r\n\r\n"))
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(body))
+}
+
+func TestPipelinedRequests(t *testing.T) {
+	reader := bufio.NewReader(&chunkReader{
+		data: "POST /one HTTP/1.1\r\nContent-Length: 5\r\n\r\nhello" +
+			"POST /two HTTP/1.1\r\nTransfer-Encoding: chunked\r\n\r\n5\r\nworld\r\n0\r\n\r\n" +
+			"GET /three HTTP/1.1\r\nHost: localhost\r\n\r\n",
+		numBytesPerRead: 1000,
+	})
+
+	for _, expected := range []struct {
+		target string
+		body   string
+	}{
+		{"/one", "hello"},
+		{"/two", "world"},
+		{"/three", ""},
+	} {
+		r, body, err := readFullRequest(reader)
+		require.NoError(t, err)
+		assert.Equal(t, expected.target, r.RequestLine.RequestTarget)
+		assert.Equal(t, expected.body, string(body))
+	}
+
+	_, err := RequestFromReader(reader)
+	assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+
+	reader = bufio.NewReader(strings.NewReader(
+		"POST /skip HTTP/1.1\r\nContent-Length: 11\r\n\r\nhello world" +
+			"GET /next HTTP/1.1\r\n\r\n"))
+	r, err := RequestFromReader(reader)
+	require.NoError(t, err)
+	require.NoError(t, r.Body.Close())
+
+	r, err = RequestFromReader(reader)
+	require.NoError(t, err)
+	assert.Equal(t, "/next", r.RequestLine.RequestTarget)
+
+	large := strings.Repeat("x", DefaultLimits.BufferSize()*2)
+	r, body, err := readFullRequest(strings.NewReader("POST /large HTTP/1.1\r\nContent-Length: " + strconv.Itoa(len(large)) + "\r\n\r\n" + large))
+	require.NoError(t, err)
+	assert.Equal(t, "/large", r.RequestLine.RequestTarget)
+	assert.Equal(t, large, string(body))
+}
+
+func TestRequestTarget(t *testing.T) {
+	r, err := RequestFromReader(strings.NewReader("GET /caf%C3%A9/menu%20items?tag=a&tag=b&q=x%26y HTTP/1.1\r\nHost: localhost:42069\r\n\r\n"))
+	require.NoError(t, err)
+	assert.Equal(t, TargetOriginForm, r.TargetForm())
+	assert.Equal(t, "/café/menu items", r.Path())
+	assert.Equal(t, "/caf%C3%A9/menu%20items", r.RawPath())
+	assert.Equal(t, "tag=a&tag=b&q=x%26y", r.RawQuery())
+	assert.Equal(t, []string{"a", "b"}, r.Query()["tag"])
+	assert.Equal(t, "x&y", r.Query().Get("q"))
+	assert.Equal(t, "localhost:42069", r.Host())
+
+	r, err = RequestFromReader(strings.NewReader("GET http://example.com:8080/a/b?c=d HTTP/1.1\r\nHost: ignored\r\n\r\n"))
+	require.NoError(t, err)
+	assert.Equal(t, TargetAbsoluteForm, r.TargetForm())
+	assert.Equal(t, "http", r.Scheme())
+	assert.Equal(t, "example.com:8080", r.Host())
+	assert.Equal(t, "/a/b", r.Path())
+	assert.Equal(t, "d", r.Query().Get("c"))
+
+	r, err = RequestFromReader(strings.NewReader("GET https://example.com HTTP/1.1\r\n\r\n"))
+	require.NoError(t, err)
+	assert.Equal(t, "/", r.Path())
+
+	r, err = RequestFromReader(strings.NewReader("CONNECT example.com:443 HTTP/1.1\r\n\r\n"))
+	require.NoError(t, err)
+	assert.Equal(t, TargetAuthorityForm, r.TargetForm())
+	assert.Equal(t, "example.com:443", r.Host())
+	assert.Equal(t, "", r.Path())
+
+	r, err = RequestFromReader(strings.NewReader("GET /a?x=1;y=2&z=3 HTTP/1.1\r\n\r\n"))
+	require.NoError(t, err)
+	assert.Equal(t, "x=1;y=2&z=3", r.RawQuery())
+	assert.Equal(t, "3", r.Query().Get("z"))
+	assert.False(t, r.Query().Has("x"))
+
+	r, err = RequestFromReader(strings.NewReader("OPTIONS * HTTP/1.1\r\n\r\n"))
+	require.NoError(t, err)
+	assert.Equal(t, TargetAsteriskForm, r.TargetForm())
+
+	for _, line := range []string{
+		"GET /page#section HTTP/1.1",
+		"GET /bad%zzescape HTTP/1.1",
+		"GET /trailing% HTTP/1.1",
+		"GET /ctl\x01char HTTP/1.1",
+		"GET * HTTP/1.1",
+		"CONNECT /path HTTP/1.1",
+		"CONNECT example.com HTTP/1.1",
+		"GET example.com:443 HTTP/1.1",
+		"GET ftp://example.com/ HTTP/1.1",
+		"GET http:///nohost HTTP/1.1",
+		"GET http://user@example.com/ HTTP/1.1",
+	} {
+		_, err := RequestFromReader(strings.NewReader(line + "\r\n\r\n"))
+		assert.ErrorIs(t, err, ErrBadRequestTarget, line)
+	}
+}
+
+func formRequest(t *testing.T, contentType, body string) *Request {
+	raw := "POST /submit?q=fromquery&name=ignored HTTP/1.1\r\n" +
+		"Host: localhost\r\n" +
+		"Content-Type: " + contentType + "\r\n" +
+		"Content-Length: " + strconv.Itoa(len(body)) + "\r\n" +
+		"\r\n" + body
+	r, err := RequestFromReader(strings.NewReader(raw))
+	require.NoError(t, err)
+	return r
+}
+
+func TestForms(t *testing.T) {
+	r := formRequest(t, "application/x-www-form-urlencoded", "name=surya&tags=a&tags=b&note=hello+world")
+	require.NoError(t, r.ParseForm())
+	assert.Equal(t, "surya", r.FormValue("name"))
+	assert.Equal(t, []string{"a", "b"}, r.PostForm()["tags"])
+	assert.Equal(t, "hello world", r.FormValue("note"))
+	assert.Equal(t, "fromquery", r.FormValue("q"))
+	assert.Nil(t, r.MultipartForm())
+
+	r = formRequest(t, "application/x-www-form-urlencoded", "bad=%zz")
+	assert.Error(t, r.ParseForm())
+
+	r = formRequest(t, "text/plain", "name=surya")
+	require.NoError(t, r.ParseForm())
+	assert.Empty(t, r.PostForm())
+	assert.Equal(t, "ignored", r.FormValue("name"))
+
+	multipartBody := "--XYZ\r\n" +
+		"Content-Disposition: form-data; name=\"title\"\r\n\r\n" +
+		"my upload\r\n" +
+		"--XYZ\r\n" +
+		"Content-Disposition: form-data; name=\"file\"; filename=\"hello.txt\"\r\n" +
+		"Content-Type: text/plain\r\n\r\n" +
+		"hello, file contents\r\n" +
+		"--XYZ--\r\n"
+
+	r = formRequest(t, "multipart/form-data; boundary=XYZ", multipartBody)
+	assert.Equal(t, "my upload", r.FormValue("title"))
+	f, fh, err := r.FormFile("file")
+	require.NoError(t, err)
+	assert.Equal(t, "hello.txt", fh.Filename)
+	contents, err := io.ReadAll(f)
+	require.NoError(t, err)
+	assert.Equal(t, "hello, file contents", string(contents))
+	require.NoError(t, f.Close())
+	_, _, err = r.FormFile("missing")
+	assert.ErrorIs(t, err, ErrMissingFile)
+	require.NoError(t, r.RemoveTempFiles())
+
+	r = formRequest(t, "multipart/form-data; boundary=XYZ", multipartBody)
+	require.NoError(t, r.ParseMultipartForm(1))
+	fh = r.MultipartForm().File["file"][0]
+	f, err = fh.Open()
+	require.NoError(t, err)
+	contents, err = io.ReadAll(f)
+	require.NoError(t, err)
+	assert.Equal(t, "hello, file contents", string(contents))
+	require.NoError(t, f.Close())
+	require.NoError(t, r.RemoveTempFiles())
+
+	r = formRequest(t, "multipart/form-data; boundary=XYZ", multipartBody)
+	mr, err := r.MultipartReader()
+	require.NoError(t, err)
+	part, err := mr.NextPart()
+	require.NoError(t, err)
+	assert.Equal(t, "title", part.FormName())
+
+	r = formRequest(t, "multipart/form-data; boundary=XYZ", "--XYZ\r\nno terminator")
+	assert.ErrorIs(t, r.ParseMultipartForm(DefaultMaxFormMemory), ErrBadMultipart)
+
+	r = formRequest(t, "multipart/form-data", multipartBody)
+	assert.ErrorIs(t, r.ParseMultipartForm(DefaultMaxFormMemory), ErrBadBoundary)
+
+	r = formRequest(t, "multipart/form-data; boundary=\""+strings.Repeat("a", 71)+"\"", multipartBody)
+	assert.ErrorIs(t, r.ParseMultipartForm(DefaultMaxFormMemory), ErrBadBoundary)
+
+	r = formRequest(t, "multipart/form-data; boundary=\"bad boundary \"", multipartBody)
+	assert.ErrorIs(t, r.ParseMultipartForm(DefaultMaxFormMemory), ErrBadBoundary)
+
+	r = formRequest(t, "application/json", "{}")
+	_, err = r.MultipartReader()
+	assert.ErrorIs(t, err, ErrNotMultipart)
+}
+
+func TestCookies(t *testing.T) {
+	r, err := RequestFromReader(strings.NewReader("GET / HTTP/1.1\r\n" +
+		"Host: localhost\r\n" +
+		"Cookie: session=abc123; theme=\"dark\"; bad name=x; empty=\r\n" +
+		"Cookie: lang=en;broken\r\n" +
+		"\r\n"))
+	require.NoError(t, err)
+
+	assert.Equal(t, []Cookie{
+		{Name: "session", Value: "abc123"},
+		{Name: "theme", Value: "dark"},
+		{Name: "empty", Value: ""},
+		{Name: "lang", Value: "en"},
+	}, r.Cookies())
+
+	c, err := r.Cookie("theme")
+	require.NoError(t, err)
+	assert.Equal(t, "dark", c.Value)
+
+	_, err = r.Cookie("missing")
+	assert.ErrorIs(t, err, ErrNoCookie)
+}
+
+func TestHTTPVersions(t *testing.T) {
+	r, err := RequestFromReader(strings.NewReader("GET / HTTP/1.0\r\n\r\n"))
+	require.NoError(t, err)
+	assert.Equal(t, "1.0", r.RequestLine.HttpVersion)
+	assert.False(t, r.KeepAlive())
+
+	r, err = RequestFromReader(strings.NewReader("GET / HTTP/1.0\r\nConnection: Keep-Alive\r\n\r\n"))
+	require.NoError(t, err)
+	assert.True(t, r.KeepAlive())
+
+	r, err = RequestFromReader(strings.NewReader("GET / HTTP/1.1\r\n\r\n"))
+	require.NoError(t, err)
+	assert.True(t, r.KeepAlive())
+
+	r, err = RequestFromReader(strings.NewReader("GET / HTTP/1.1\r\nConnection: close\r\n\r\n"))
+	require.NoError(t, err)
+	assert.False(t, r.KeepAlive())
+
+	_, body, err := readFullRequest(strings.NewReader("POST / HTTP/1.0\r\nContent-Length: 5\r\n\r\nhello"))
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(body))
+
+	_, err = RequestFromReader(strings.NewReader("POST / HTTP/1.0\r\nTransfer-Encoding: chunked\r\n\r\n0\r\n\r\n"))
+	assert.ErrorIs(t, err, ErrBadTransferEncoding)
+
+	for _, version := range []string{"HTTP/0.9", "HTTP/2.0", "HTTP/3.0"} {
+		_, err = RequestFromReader(strings.NewReader("GET / " + version + "\r\n\r\n"))
+		assert.ErrorIs(t, err, ErrUnsupportedVersion, version)
+	}
+
+	for _, version := range []string{"HTTP/1", "HTTP/1.10", "HTTP/x.y", "HTTP/11"} {
+		_, err = RequestFromReader(strings.NewReader("GET / " + version + "\r\n\r\n"))
+		assert.ErrorIs(t, err, ErrBadRequestLine, version)
+	}
 }
@@ -0,0 +1,163 @@
+package request
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+type TargetForm int
+
+const (
+	TargetOriginForm TargetForm = iota
+	TargetAbsoluteForm
+	TargetAuthorityForm
+	TargetAsteriskForm
+)
+
+type target struct {
+	form     TargetForm
+	scheme   string
+	host     string
+	rawPath  string
+	path     string
+	rawQuery string
+	query    url.Values
+}
+
+func (r *Request) TargetForm() TargetForm {
+	return r.target.form
+}
+
+func (r *Request) Scheme() string {
+	return r.target.scheme
+}
+
+func (r *Request) Host() string {
+	if r.target.host != "" {
+		return r.target.host
+	}
+	return r.Headers.Get("host")
+}
+
+func (r *Request) Path() string {
+	return r.target.path
+}
+
+func (r *Request) RawPath() string {
+	return r.target.rawPath
+}
+
+func (r *Request) RawQuery() string {
+	return r.target.rawQuery
+}
+
+func (r *Request) Query() url.Values {
+	return r.target.query
+}
+
+func parseTarget(method, raw string) (target, error) {
+	if err := validateTargetChars(raw); err != nil {
+		return target{}, err
+	}
+
+	switch {
+	case raw == "*":
+		if method != "OPTIONS" {
+			return target{}, fmt.Errorf("%w: asterisk-form is only allowed for OPTIONS", ErrBadRequestTarget)
+		}
+		return target{form: TargetAsteriskForm, query: url.Values{}}, nil
+
+	case method == "CONNECT":
+		if !isAuthority(raw) {
+			return target{}, fmt.Errorf("%w: CONNECT requires authority-form host:port", ErrBadRequestTarget)
+		}
+		return target{form: TargetAuthorityForm, host: raw, query: url.Values{}}, nil
+
+	case strings.HasPrefix(raw, "/"):
+		t := target{form: TargetOriginForm}
+		return t, t.setPathAndQuery(raw)
+
+	default:
+		return parseAbsoluteTarget(raw)
+	}
+}
+
+func parseAbsoluteTarget(raw string) (target, error) {
+	scheme, rest, found := strings.Cut(raw, "://")
+	scheme = strings.ToLower(scheme)
+	if !found || (scheme != "http" && scheme != "https") {
+		return target{}, fmt.Errorf("%w: %q", ErrBadRequestTarget, raw)
+	}
+
+	host := rest
+	pathAndQuery := "/"
+	if idx := strings.IndexAny(rest, "/?"); idx != -1 {
+		host = rest[:idx]
+		pathAndQuery = rest[idx:]
+		if strings.HasPrefix(pathAndQuery, "?") {
+			pathAndQuery = "/" + pathAndQuery
+		}
+	}
+
+	if host == "" || strings.Contains(host, "@") {
+		return target{}, fmt.Errorf("%w: invalid authority %q", ErrBadRequestTarget, host)
+	}
+
+	t := target{form: TargetAbsoluteForm, scheme: scheme, host: host}
+	return t, t.setPathAndQuery(pathAndQuery)
+}
+
+func (t *target) setPathAndQuery(raw string) error {
+	rawPath, rawQuery, _ := strings.Cut(raw, "?")
+
+	path, err := url.PathUnescape(rawPath)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrBadRequestTarget, err)
+	}
+
+	query, _ := url.ParseQuery(rawQuery)
+
+	t.rawPath = rawPath
+	t.path = path
+	t.rawQuery = rawQuery
+	t.query = query
+	return nil
+}
+
+func validateTargetChars(raw string) error {
+	if raw == "" {
+		return fmt.Errorf("%w: empty target", ErrBadRequestTarget)
+	}
+
+	for i := 0; i < len(raw); i++ {
+		c := raw[i]
+		switch {
+		case c == '#':
+			return fmt.Errorf("%w: fragment not allowed", ErrBadRequestTarget)
+		case c <= ' ' || c >= 0x7f:
+			return fmt.Errorf("%w: invalid character %q", ErrBadRequestTarget, c)
+		case c == '%':
+			if i+2 >= len(raw) || !isHex(raw[i+1]) || !isHex(raw[i+2]) {
+				return fmt.Errorf("%w: invalid percent-encoding", ErrBadRequestTarget)
+			}
+		}
+	}
+
+	return nil
+}
+
+func isAuthority(raw string) bool {
+	idx := strings.LastIndex(raw, ":")
+	if idx <= 0 || idx == len(raw)-1 || strings.ContainsAny(raw, "/?@") {
+		return false
+	}
+
+	port := raw[idx+1:]
+	return strings.Trim(port, "0123456789") == ""
+}
+
+func isHex(c byte) bool {
+	_, ok := hexDigit(c)
+	return ok
+}
@@ -0,0 +1,150 @@
+package response
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"surya.httpfromtcp/internal/headers"
+)
+
+var ErrInvalidCookie = errors.New("invalid cookie")
+
+type SameSite int
+
+const (
+	SameSiteDefault SameSite = iota
+	SameSiteLax
+	SameSiteStrict
+	SameSiteNone
+)
+
+type Cookie struct {
+	Name        string
+	Value       string
+	Expires     time.Time
+	MaxAge      int
+	Domain      string
+	Path        string
+	Secure      bool
+	HttpOnly    bool
+	SameSite    SameSite
+	Partitioned bool
+}
+
+func (c *Cookie) Validate() error {
+	if !headers.IsToken(c.Name) {
+		return fmt.Errorf("%w: invalid name %q", ErrInvalidCookie, c.Name)
+	}
+
+	if !headers.IsCookieValue(c.Value) {
+		return fmt.Errorf("%w: invalid value for %q", ErrInvalidCookie, c.Name)
+	}
+
+	if !isAttributeValue(c.Domain) || !isAttributeValue(c.Path) {
+		return fmt.Errorf("%w: invalid domain or path for %q", ErrInvalidCookie, c.Name)
+	}
+
+	if c.Path != "" && !strings.HasPrefix(c.Path, "/") {
+		return fmt.Errorf("%w: path must begin with / for %q", ErrInvalidCookie, c.Name)
+	}
+
+	if !c.Expires.IsZero() && c.Expires.Year() < 1601 {
+		return fmt.Errorf("%w: expires before 1601 for %q", ErrInvalidCookie, c.Name)
+	}
+
+	if (c.SameSite == SameSiteNone || c.Partitioned) && !c.Secure {
+		return fmt.Errorf("%w: SameSite=None and Partitioned require Secure for %q", ErrInvalidCookie, c.Name)
+	}
+
+	switch {
+	case strings.HasPrefix(c.Name, "__Secure-"):
+		if !c.Secure {
+			return fmt.Errorf("%w: __Secure- prefix requires Secure", ErrInvalidCookie)
+		}
+	case strings.HasPrefix(c.Name, "__Host-"):
+		if !c.Secure || c.Domain != "" || c.Path != "/" {
+			return fmt.Errorf("%w: __Host- prefix requires Secure, Path=/ and no Domain", ErrInvalidCookie)
+		}
+	}
+
+	return nil
+}
+
+func (c *Cookie) String() string {
+	var b strings.Builder
+	b.WriteString(c.Name)
+	b.WriteByte('=')
+	b.WriteString(c.Value)
+
+	if !c.Expires.IsZero() {
+		b.WriteString("; Expires=")
+		b.WriteString(c.Expires.UTC().Format(TimeFormat))
+	}
+
+	switch {
+	case c.MaxAge > 0:
+		b.WriteString("; Max-Age=")
+		b.WriteString(strconv.Itoa(c.MaxAge))
+	case c.MaxAge < 0:
+		b.WriteString("; Max-Age=0")
+	}
+
+	if c.Domain != "" {
+		b.WriteString("; Domain=")
+		b.WriteString(strings.TrimPrefix(c.Domain, "."))
+	}
+
+	if c.Path != "" {
+		b.WriteString("; Path=")
+		b.WriteString(c.Path)
+	}
+
+	if c.Secure {
+		b.WriteString("; Secure")
+	}
+
+	if c.HttpOnly {
+		b.WriteString("; HttpOnly")
+	}
+
+	switch c.SameSite {
+	case SameSiteLax:
+		b.WriteString("; SameSite=Lax")
+	case SameSiteStrict:
+		b.WriteString("; SameSite=Strict")
+	case SameSiteNone:
+		b.WriteString("; SameSite=None")
+	}
+
+	if c.Partitioned {
+		b.WriteString("; Partitioned")
+	}
+
+	return b.String()
+}
+
+func SetCookie(h *headers.Headers, c *Cookie) error {
+	if err := c.Validate(); err != nil {
+		return err
+	}
+
+	h.Add("set-cookie", c.String())
+	return nil
+}
+
+func (w *Writer) SetCookie(c *Cookie) error {
+	return SetCookie(w.header, c)
+}
+
+func isAttributeValue(value string) bool {
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		if c < ' ' || c == 0x7f || c == ';' {
+			return false
+		}
+	}
+	return true
+}
@@ -1,145 +1,388 @@
 package response
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"io"
 	"strconv"
+	"strings"
 
 	"surya.httpfromtcp/internal/headers"
 )
 
-type StatusCode int
-
-const (
-	StatusOK                  StatusCode = 200
-	StatusBadRequest          StatusCode = 400
-	StatusInternalServerError StatusCode = 500
-)
+const TimeFormat = "Mon, 02 Jan 2006 15:04:05 GMT"
 
 const (
 	writerStateInitialized = iota
 	writerStateStatusWritten
 	writerStateHeadersWritten
 	writerStateBodyWritten
+	writerStateChunkedBody
+	writerStateChunkedBodyDone
+	writerStateTrailersWritten
 )
 
 type Writer struct {
-	w     io.Writer
-	state int
+	w             io.Writer
+	state         int
+	statusLine    []byte
+	statusCode    StatusCode
+	closeConn     bool
+	chunked       bool
+	http10        bool
+	head          bool
+	unframed      bool
+	contentLength int
+	bodyWritten   int
+	header        *headers.Headers
+	headerHooks   []func(h *headers.Headers)
+	bodyEncoder   func(h *headers.Headers, dst io.Writer) io.WriteCloser
+	encoder       io.WriteCloser
 }
 
 func NewWriter(w io.Writer) *Writer {
 	return &Writer{
-		w:     w,
-		state: writerStateInitialized,
+		w:             w,
+		state:         writerStateInitialized,
+		contentLength: -1,
+		header:        headers.NewHeaders(),
 	}
 }
 
+func (w *Writer) SetRequestVersion(version string) {
+	w.http10 = version == "1.0"
+}
+
+func (w *Writer) SetRequestMethod(method string) {
+	w.head = method == "HEAD"
+}
+
 func (w *Writer) WriteStatusLine(statusCode StatusCode) error {
+	return w.WriteStatusLineWithReason(statusCode, StatusText(statusCode))
+}
+
+func (w *Writer) WriteStatusLineWithReason(statusCode StatusCode, reasonPhrase string) error {
 	if w.state != writerStateInitialized {
 		return errors.New("status line already written")
 	}
 
-	var reasonPhrase string
-	switch statusCode {
-	case StatusOK:
-		reasonPhrase = "OK"
-	case StatusBadRequest:
-		reasonPhrase = "Bad Request"
-	case StatusInternalServerError:
-		reasonPhrase = "Internal Server Error"
-	default:
-		reasonPhrase = ""
+	var b bytes.Buffer
+	if err := WriteStatusLineWithReason(&b, statusCode, reasonPhrase); err != nil {
+		return err
 	}
 
-	var statusLine string
-	if reasonPhrase != "" {
-		statusLine = fmt.Sprintf("HTTP/1.1 %d %s\r\n", statusCode, reasonPhrase)
-	} else {
-		statusLine = fmt.Sprintf("HTTP/1.1 %d \r\n", statusCode)
+	w.statusLine = b.Bytes()
+	w.statusCode = statusCode
+	w.state = writerStateStatusWritten
+	return nil
+}
+
+func (w *Writer) WriteInformational(statusCode StatusCode, h *headers.Headers) error {
+	if w.state != writerStateInitialized {
+		return errors.New("informational responses must precede the final status line")
 	}
 
-	_, err := w.w.Write([]byte(statusLine))
-	if err != nil {
+	if statusCode < 100 || statusCode > 199 || statusCode == StatusSwitchingProtocols {
+		return fmt.Errorf("invalid informational status code: %d", statusCode)
+	}
+
+	if w.http10 {
+		return nil
+	}
+
+	var b bytes.Buffer
+	if err := WriteStatusLine(&b, statusCode); err != nil {
+		return err
+	}
+	if err := WriteHeaders(&b, h); err != nil {
 		return err
 	}
 
-	w.state = writerStateStatusWritten
-	return nil
+	_, err := w.w.Write(b.Bytes())
+	return err
 }
 
-func (w *Writer) WriteHeaders(headers headers.Headers) error {
+func (w *Writer) Header() *headers.Headers {
+	return w.header
+}
+
+func (w *Writer) OnWriteHeaders(fn func(h *headers.Headers)) {
+	w.headerHooks = append(w.headerHooks, fn)
+}
+
+// fn runs after the header hooks and may edit h. When it returns a non-nil
+// encoder, the body is written through it using chunked framing.
+func (w *Writer) SetBodyEncoder(fn func(h *headers.Headers, dst io.Writer) io.WriteCloser) {
+	w.bodyEncoder = fn
+}
+
+func (w *Writer) WriteHeaders(h *headers.Headers) error {
 	if w.state != writerStateStatusWritten {
 		return errors.New("headers must be written after status line and before body")
 	}
 
-	for key, value := range headers {
-		line := fmt.Sprintf("%s: %s\r\n", key, value)
-		_, err := w.w.Write([]byte(line))
-		if err != nil {
-			return err
+	merged := w.header.Clone()
+	for key := range h.All() {
+		if !strings.EqualFold(key, "set-cookie") {
+			merged.Del(key)
 		}
 	}
-	_, err := w.w.Write([]byte("\r\n"))
-	if err != nil {
+	for key, value := range h.All() {
+		merged.Add(key, value)
+	}
+
+	for _, hook := range w.headerHooks {
+		hook(merged)
+	}
+
+	if w.bodyEncoder != nil && bodyAllowed(w.statusCode) {
+		if enc := w.bodyEncoder(merged, chunkWriter{w}); enc != nil {
+			merged.Del("content-length")
+			if !merged.HasToken("transfer-encoding", "chunked") {
+				merged.Set("transfer-encoding", "chunked")
+			}
+			w.encoder = enc
+		}
+	}
+
+	w.chunked = merged.HasToken("transfer-encoding", "chunked")
+	if w.chunked && w.http10 {
+		merged.Del("transfer-encoding")
+		merged.Del("trailer")
+		merged.Set("connection", "close")
+		w.unframed = true
+	}
+
+	var b bytes.Buffer
+	b.Write(w.statusLine)
+	if err := WriteHeaders(&b, merged); err != nil {
 		return err
 	}
 
+	if _, err := w.w.Write(b.Bytes()); err != nil {
+		return err
+	}
+	w.statusLine = nil
+
+	if w.head {
+		w.w = io.Discard
+	}
+
+	w.closeConn = merged.HasToken("connection", "close")
+	if contentLength, err := strconv.Atoi(merged.Get("content-length")); err == nil && contentLength >= 0 {
+		w.contentLength = contentLength
+	}
+
 	w.state = writerStateHeadersWritten
 	return nil
 }
 
 func (w *Writer) WriteBody(p []byte) (int, error) {
-	if w.state != writerStateHeadersWritten {
+	if w.encoder != nil {
+		return w.WriteChunkedBody(p)
+	}
+
+	if w.state != writerStateHeadersWritten && w.state != writerStateBodyWritten {
 		return 0, errors.New("body must be written after headers")
 	}
 
 	n, err := w.w.Write(p)
+	w.bodyWritten += n
 	w.state = writerStateBodyWritten
 	return n, err
 }
 
-func GetDefaultHeaders(contentLen int) headers.Headers {
-	h := make(headers.Headers)
-	h["content-length"] = strconv.Itoa(contentLen)
-	h["connection"] = "close"
-	h["content-type"] = "text/plain"
+func (w *Writer) WriteChunkedBody(p []byte) (int, error) {
+	if w.state != writerStateHeadersWritten && w.state != writerStateChunkedBody {
+		return 0, errors.New("chunked body must be written after headers")
+	}
+
+	if !w.chunked {
+		return 0, errors.New("chunked body requires transfer-encoding: chunked header")
+	}
+
+	w.state = writerStateChunkedBody
+
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	if w.encoder != nil {
+		return w.encoder.Write(p)
+	}
+
+	return w.writeChunk(p)
+}
+
+func (w *Writer) writeChunk(p []byte) (int, error) {
+	if w.unframed {
+		n, err := w.w.Write(p)
+		w.bodyWritten += n
+		return n, err
+	}
+
+	_, err := fmt.Fprintf(w.w, "%x\r\n", len(p))
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := w.w.Write(p)
+	w.bodyWritten += n
+	if err != nil {
+		return n, err
+	}
+
+	_, err = w.w.Write([]byte("\r\n"))
+	return n, err
+}
+
+func (w *Writer) WriteChunkedBodyDone() (int, error) {
+	if w.state != writerStateHeadersWritten && w.state != writerStateChunkedBody {
+		return 0, errors.New("chunked body done must be written after headers")
+	}
+
+	if !w.chunked {
+		return 0, errors.New("chunked body requires transfer-encoding: chunked header")
+	}
+
+	if w.encoder != nil {
+		if err := w.encoder.Close(); err != nil {
+			return 0, err
+		}
+	}
+
+	if w.unframed {
+		w.state = writerStateChunkedBodyDone
+		return 0, nil
+	}
+
+	n, err := w.w.Write([]byte("0\r\n"))
+	if err != nil {
+		return n, err
+	}
+
+	w.state = writerStateChunkedBodyDone
+	return n, nil
+}
+
+func (w *Writer) WriteTrailers(h *headers.Headers) error {
+	if w.state != writerStateChunkedBodyDone {
+		return errors.New("trailers must be written after chunked body done")
+	}
+
+	if !w.unframed {
+		if err := WriteHeaders(w.w, h); err != nil {
+			return err
+		}
+	}
+
+	w.state = writerStateTrailersWritten
+	return nil
+}
+
+func (w *Writer) Finish() error {
+	if !w.chunked {
+		return nil
+	}
+
+	switch w.state {
+	case writerStateHeadersWritten, writerStateChunkedBody:
+		if _, err := w.WriteChunkedBodyDone(); err != nil {
+			return err
+		}
+		return w.WriteTrailers(headers.NewHeaders())
+	case writerStateChunkedBodyDone:
+		return w.WriteTrailers(headers.NewHeaders())
+	default:
+		return nil
+	}
+}
+
+func (w *Writer) Written() bool {
+	return w.state != writerStateInitialized
+}
+
+func (w *Writer) HeadersWritten() bool {
+	return w.state >= writerStateHeadersWritten
+}
+
+func (w *Writer) StatusCode() StatusCode {
+	return w.statusCode
+}
+
+func (w *Writer) BytesWritten() int {
+	return w.bodyWritten
+}
+
+func (w *Writer) KeepAlive() bool {
+	if w.state < writerStateHeadersWritten || w.closeConn {
+		return false
+	}
+
+	if w.head || !bodyAllowed(w.statusCode) {
+		return true
+	}
+
+	if w.chunked {
+		return w.state == writerStateTrailersWritten
+	}
+
+	return w.contentLength >= 0 && w.bodyWritten == w.contentLength
+}
+
+func bodyAllowed(statusCode StatusCode) bool {
+	return statusCode >= StatusOK && statusCode != StatusNoContent && statusCode != StatusNotModified
+}
+
+func GetDefaultHeaders(contentLen int) *headers.Headers {
+	h := headers.NewHeaders()
+	h.Set("content-length", strconv.Itoa(contentLen))
+	h.Set("content-type", "text/plain")
 	return h
 }
 
 func WriteStatusLine(w io.Writer, statusCode StatusCode) error {
-	var reasonPhrase string
-	switch statusCode {
-	case StatusOK:
-		reasonPhrase = "OK"
-	case StatusBadRequest:
-		reasonPhrase = "Bad Request"
-	case StatusInternalServerError:
-		reasonPhrase = "Internal Server Error"
-	default:
-		reasonPhrase = ""
+	return WriteStatusLineWithReason(w, statusCode, StatusText(statusCode))
+}
+
+func WriteStatusLineWithReason(w io.Writer, statusCode StatusCode, reasonPhrase string) error {
+	if statusCode < 100 || statusCode > 999 {
+		return fmt.Errorf("invalid status code: %d", statusCode)
 	}
 
-	var statusLine string
-	if reasonPhrase != "" {
-		statusLine = fmt.Sprintf("HTTP/1.1 %d %s\r\n", statusCode, reasonPhrase)
-	} else {
-		statusLine = fmt.Sprintf("HTTP/1.1 %d \r\n", statusCode)
+	for i := 0; i < len(reasonPhrase); i++ {
+		c := reasonPhrase[i]
+		if c != '\t' && (c < ' ' || c == 0x7f) {
+			return fmt.Errorf("invalid reason phrase: %q", reasonPhrase)
+		}
 	}
 
-	_, err := w.Write([]byte(statusLine))
+	_, err := fmt.Fprintf(w, "HTTP/1.1 %d %s\r\n", statusCode, reasonPhrase)
 	return err
 }
 
-func WriteHeaders(w io.Writer, headers headers.Headers) error {
-	for key, value := range headers {
-		line := fmt.Sprintf("%s: %s\r\n", key, value)
-		_, err := w.Write([]byte(line))
-		if err != nil {
+func WriteHeaders(w io.Writer, h *headers.Headers) error {
+	var b bytes.Buffer
+	for key, value := range h.All() {
+		if err := headers.ValidateField(key, value); err != nil {
 			return err
 		}
+		fmt.Fprintf(&b, "%s: %s\r\n", key, value)
 	}
-	_, err := w.Write([]byte("\r\n"))
+	b.WriteString("\r\n")
+
+	_, err := w.Write(b.Bytes())
 	return err
 }
+
+type chunkWriter struct {
+	w *Writer
+}
+
+func (c chunkWriter) Write(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	return c.w.writeChunk(p)
+}
@@ -0,0 +1,374 @@
+package response
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"surya.httpfromtcp/internal/headers"
+)
+
+func TestWriterChunkedBody(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	h := headers.NewHeaders()
+	h.Set("transfer-encoding", "chunked")
+	h.Set("trailer", "x-checksum")
+
+	require.NoError(t, w.WriteStatusLine(StatusOK))
+	require.NoError(t, w.WriteHeaders(h))
+
+	n, err := w.WriteChunkedBody([]byte("hello "))
+	require.NoError(t, err)
+	assert.Equal(t, 6, n)
+
+	n, err = w.WriteChunkedBody([]byte{})
+	require.NoError(t, err)
+	assert.Equal(t, 0, n)
+
+	n, err = w.WriteChunkedBody([]byte("world, this is chunked!"))
+	require.NoError(t, err)
+	assert.Equal(t, 23, n)
+
+	_, err = w.WriteChunkedBodyDone()
+	require.NoError(t, err)
+
+	_, err = w.WriteChunkedBody([]byte("too late"))
+	require.Error(t, err)
+
+	trailers := headers.NewHeaders()
+	trailers.Set("x-checksum", "abc123")
+	require.NoError(t, w.WriteTrailers(trailers))
+	require.Error(t, w.WriteTrailers(trailers))
+
+	assert.True(t, w.KeepAlive())
+	assert.True(t, bytes.HasSuffix(buf.Bytes(), []byte("\r\n\r\n"+
+		"6\r\nhello \r\n"+
+		"17\r\nworld, this is chunked!\r\n"+
+		"0\r\n"+
+		"x-checksum: abc123\r\n"+
+		"\r\n")))
+}
+
+func TestWriterChunkedBodyRequiresHeader(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+
+	_, err := w.WriteChunkedBody([]byte("early"))
+	require.Error(t, err)
+
+	require.NoError(t, w.WriteStatusLine(StatusOK))
+	require.NoError(t, w.WriteHeaders(GetDefaultHeaders(0)))
+
+	_, err = w.WriteChunkedBody([]byte("hello"))
+	require.Error(t, err)
+	_, err = w.WriteChunkedBodyDone()
+	require.Error(t, err)
+}
+
+func TestWriterFinish(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	h := headers.NewHeaders()
+	h.Set("transfer-encoding", "chunked")
+
+	require.NoError(t, w.WriteStatusLine(StatusOK))
+	require.NoError(t, w.WriteHeaders(h))
+	_, err := w.WriteChunkedBody([]byte("abc"))
+	require.NoError(t, err)
+	assert.False(t, w.KeepAlive())
+
+	require.NoError(t, w.Finish())
+	assert.True(t, w.KeepAlive())
+	assert.True(t, bytes.HasSuffix(buf.Bytes(), []byte("3\r\nabc\r\n0\r\n\r\n")))
+}
+
+func TestWriterHeaderOrder(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	w.Header().Set("Server", "httpfromtcp")
+	w.Header().Set("Content-Type", "application/octet-stream")
+
+	h := headers.NewHeaders()
+	h.Set("Content-Type", "text/html")
+	h.Add("Set-Cookie", "a=1")
+	h.Add("Set-Cookie", "b=2")
+	h.Set("Content-Length", "0")
+
+	require.NoError(t, w.WriteStatusLine(StatusOK))
+	require.NoError(t, w.WriteHeaders(h))
+	assert.Equal(t, "HTTP/1.1 200 OK\r\n"+
+		"Server: httpfromtcp\r\n"+
+		"Content-Type: text/html\r\n"+
+		"Set-Cookie: a=1\r\n"+
+		"Set-Cookie: b=2\r\n"+
+		"Content-Length: 0\r\n"+
+		"\r\n", buf.String())
+}
+
+func TestWriterRejectsInvalidHeaders(t *testing.T) {
+	for _, tt := range []struct {
+		name  string
+		value string
+	}{
+		{"X-Echo", "hello\r\nSet-Cookie: admin=true"},
+		{"X-Echo", "line\nbreak"},
+		{"X-Echo", "nul\x00byte"},
+		{"X Echo", "space in name"},
+		{"X-Échø", "non-token name"},
+		{"", "empty name"},
+	} {
+		var buf bytes.Buffer
+		w := NewWriter(&buf)
+		h := GetDefaultHeaders(0)
+		h.Set(tt.name, tt.value)
+
+		require.NoError(t, w.WriteStatusLine(StatusOK))
+
+		err := w.WriteHeaders(h)
+		require.Error(t, err, tt.name)
+		assert.ErrorIs(t, err, headers.ErrInvalidHeader)
+		assert.Empty(t, buf.String())
+	}
+
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	h := headers.NewHeaders()
+	h.Set("transfer-encoding", "chunked")
+	require.NoError(t, w.WriteStatusLine(StatusOK))
+	require.NoError(t, w.WriteHeaders(h))
+	_, err := w.WriteChunkedBodyDone()
+	require.NoError(t, err)
+
+	trailers := headers.NewHeaders()
+	trailers.Set("x-checksum", "abc\r\n\r\nsmuggled")
+	assert.ErrorIs(t, w.WriteTrailers(trailers), headers.ErrInvalidHeader)
+}
+
+func TestStatusLine(t *testing.T) {
+	tests := []struct {
+		statusCode StatusCode
+		expected   string
+	}{
+		{StatusOK, "HTTP/1.1 200 OK\r\n"},
+		{StatusNoContent, "HTTP/1.1 204 No Content\r\n"},
+		{StatusPermanentRedirect, "HTTP/1.1 308 Permanent Redirect\r\n"},
+		{StatusTooManyRequests, "HTTP/1.1 429 Too Many Requests\r\n"},
+		{StatusNetworkAuthenticationRequired, "HTTP/1.1 511 Network Authentication Required\r\n"},
+		{299, "HTTP/1.1 299 \r\n"},
+	}
+
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		require.NoError(t, WriteStatusLine(&buf, tt.statusCode))
+		assert.Equal(t, tt.expected, buf.String())
+
+		buf.Reset()
+		w := NewWriter(&buf)
+		require.NoError(t, w.WriteStatusLine(tt.statusCode))
+		assert.Empty(t, buf.String())
+		require.NoError(t, w.WriteHeaders(headers.NewHeaders()))
+		assert.Equal(t, tt.expected+"\r\n", buf.String())
+	}
+
+	assert.Equal(t, "Unprocessable Content", StatusText(StatusUnprocessableContent))
+	assert.Equal(t, "", StatusText(299))
+}
+
+func TestStatusLineWithReason(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	require.NoError(t, w.WriteStatusLineWithReason(StatusOK, "Totally Fine"))
+	require.NoError(t, w.WriteHeaders(headers.NewHeaders()))
+	assert.Equal(t, "HTTP/1.1 200 Totally Fine\r\n\r\n", buf.String())
+	assert.Equal(t, StatusOK, w.StatusCode())
+
+	buf.Reset()
+	w = NewWriter(&buf)
+	require.Error(t, w.WriteStatusLineWithReason(StatusOK, "OK\r\nSet-Cookie: x=1"))
+	require.Error(t, w.WriteStatusLineWithReason(42, "Too Short"))
+	assert.Empty(t, buf.String())
+	require.NoError(t, w.WriteStatusLine(StatusOK))
+}
+
+func TestSetCookie(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+
+	require.NoError(t, w.SetCookie(&Cookie{
+		Name:     "session",
+		Value:    "abc123",
+		Expires:  time.Date(2030, time.January, 2, 15, 4, 5, 0, time.FixedZone("X", 3600)),
+		MaxAge:   3600,
+		Domain:   ".example.com",
+		Path:     "/",
+		Secure:   true,
+		HttpOnly: true,
+		SameSite: SameSiteLax,
+	}))
+	require.NoError(t, w.SetCookie(&Cookie{Name: "theme", Value: "dark", MaxAge: -1}))
+	require.NoError(t, w.SetCookie(&Cookie{Name: "__Host-id", Value: "1", Path: "/", Secure: true, SameSite: SameSiteNone, Partitioned: true}))
+
+	require.NoError(t, w.WriteStatusLine(StatusOK))
+	require.NoError(t, w.WriteHeaders(GetDefaultHeaders(0)))
+
+	assert.Equal(t, "HTTP/1.1 200 OK\r\n"+
+		"set-cookie: session=abc123; Expires=Wed, 02 Jan 2030 14:04:05 GMT; Max-Age=3600; Domain=example.com; Path=/; Secure; HttpOnly; SameSite=Lax\r\n"+
+		"set-cookie: theme=dark; Max-Age=0\r\n"+
+		"set-cookie: __Host-id=1; Path=/; Secure; SameSite=None; Partitioned\r\n"+
+		"content-length: 0\r\n"+
+		"content-type: text/plain\r\n"+
+		"\r\n", buf.String())
+
+	for _, c := range []*Cookie{
+		{Name: "", Value: "x"},
+		{Name: "bad name", Value: "x"},
+		{Name: "a", Value: "has space"},
+		{Name: "a", Value: "semi;colon"},
+		{Name: "a", Value: "x", Path: "/a;b"},
+		{Name: "a", Value: "x", Path: "relative"},
+		{Name: "a", Value: "x", SameSite: SameSiteNone},
+		{Name: "a", Value: "x", Partitioned: true},
+		{Name: "__Secure-a", Value: "x"},
+		{Name: "__Host-a", Value: "x", Secure: true, Path: "/", Domain: "example.com"},
+		{Name: "a", Value: "x", Expires: time.Date(1600, time.January, 1, 0, 0, 0, 0, time.UTC)},
+	} {
+		assert.ErrorIs(t, SetCookie(headers.NewHeaders(), c), ErrInvalidCookie, c.Name)
+	}
+}
+
+func TestSetCookieWithHandlerHeaders(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+
+	require.NoError(t, w.SetCookie(&Cookie{Name: "a", Value: "1"}))
+	h := GetDefaultHeaders(0)
+	require.NoError(t, SetCookie(h, &Cookie{Name: "b", Value: "2"}))
+
+	require.NoError(t, w.WriteStatusLine(StatusOK))
+	require.NoError(t, w.WriteHeaders(h))
+
+	assert.Equal(t, "HTTP/1.1 200 OK\r\n"+
+		"set-cookie: a=1\r\n"+
+		"content-length: 0\r\n"+
+		"content-type: text/plain\r\n"+
+		"set-cookie: b=2\r\n"+
+		"\r\n", buf.String())
+}
+
+func TestWriteInformational(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+
+	h := headers.NewHeaders()
+	h.Add("link", "</style.css>; rel=preload; as=style")
+	h.Add("link", "</script.js>; rel=preload; as=script")
+	require.NoError(t, w.WriteInformational(StatusEarlyHints, h))
+	require.NoError(t, w.WriteInformational(StatusContinue, nil))
+	assert.False(t, w.Written())
+
+	assert.Error(t, w.WriteInformational(StatusSwitchingProtocols, nil))
+	assert.Error(t, w.WriteInformational(StatusOK, nil))
+
+	require.NoError(t, w.WriteStatusLine(StatusOK))
+	require.NoError(t, w.WriteHeaders(GetDefaultHeaders(0)))
+	assert.Error(t, w.WriteInformational(StatusContinue, nil))
+
+	assert.Equal(t, "HTTP/1.1 103 Early Hints\r\n"+
+		"link: </style.css>; rel=preload; as=style\r\n"+
+		"link: </script.js>; rel=preload; as=script\r\n"+
+		"\r\n"+
+		"HTTP/1.1 100 Continue\r\n"+
+		"\r\n"+
+		"HTTP/1.1 200 OK\r\n"+
+		"content-length: 0\r\n"+
+		"content-type: text/plain\r\n"+
+		"\r\n", buf.String())
+}
+
+type upperEncoder struct {
+	dst io.Writer
+}
+
+func (e upperEncoder) Write(p []byte) (int, error) {
+	return e.dst.Write(bytes.ToUpper(p))
+}
+
+func (e upperEncoder) Close() error {
+	_, err := e.dst.Write([]byte("!"))
+	return err
+}
+
+func TestWriterBodyEncoder(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	w.SetBodyEncoder(func(h *headers.Headers, dst io.Writer) io.WriteCloser {
+		h.Set("content-encoding", "upper")
+		return upperEncoder{dst}
+	})
+
+	require.NoError(t, w.WriteStatusLine(StatusOK))
+	require.NoError(t, w.WriteHeaders(GetDefaultHeaders(11)))
+	_, err := w.WriteBody([]byte("hello "))
+	require.NoError(t, err)
+	_, err = w.WriteBody([]byte("world"))
+	require.NoError(t, err)
+	require.NoError(t, w.Finish())
+	assert.True(t, w.KeepAlive())
+
+	assert.Equal(t, "HTTP/1.1 200 OK\r\n"+
+		"content-type: text/plain\r\n"+
+		"content-encoding: upper\r\n"+
+		"transfer-encoding: chunked\r\n"+
+		"\r\n"+
+		"6\r\nHELLO \r\n"+
+		"5\r\nWORLD\r\n"+
+		"1\r\n!\r\n"+
+		"0\r\n\r\n", buf.String())
+}
+
+func TestWriterHeadDiscardsBody(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	w.SetRequestMethod("HEAD")
+
+	require.NoError(t, w.WriteStatusLine(StatusOK))
+	require.NoError(t, w.WriteHeaders(GetDefaultHeaders(5)))
+	n, err := w.WriteBody([]byte("hello"))
+	require.NoError(t, err)
+	assert.Equal(t, 5, n)
+	assert.True(t, w.KeepAlive())
+
+	assert.Equal(t, "HTTP/1.1 200 OK\r\n"+
+		"content-length: 5\r\n"+
+		"content-type: text/plain\r\n"+
+		"\r\n", buf.String())
+
+	buf.Reset()
+	w = NewWriter(&buf)
+	w.SetRequestMethod("HEAD")
+	require.NoError(t, w.WriteStatusLine(StatusOK))
+	require.NoError(t, w.WriteHeaders(GetDefaultHeaders(5)))
+	assert.True(t, w.KeepAlive())
+}
+
+func TestWriterBodyInParts(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+
+	require.NoError(t, w.WriteStatusLine(StatusOK))
+	require.NoError(t, w.WriteHeaders(GetDefaultHeaders(11)))
+	_, err := w.WriteBody([]byte("hello "))
+	require.NoError(t, err)
+	assert.False(t, w.KeepAlive())
+	_, err = w.WriteBody([]byte("world"))
+	require.NoError(t, err)
+	assert.True(t, w.KeepAlive())
+	assert.Equal(t, 11, w.BytesWritten())
+	assert.True(t, strings.HasSuffix(buf.String(), "\r\n\r\nhello world"))
+}
@@ -0,0 +1,138 @@
+package server
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"mime"
+	"slices"
+	"strconv"
+	"strings"
+
+	"surya.httpfromtcp/internal/headers"
+	"surya.httpfromtcp/internal/request"
+	"surya.httpfromtcp/internal/response"
+)
+
+const DefaultCompressMinSize = 1024
+
+var compressibleTypes = []string{
+	"application/javascript",
+	"application/json",
+	"application/xml",
+	"image/svg+xml",
+}
+
+func Compress(minSize int) Middleware {
+	return func(next Handler) Handler {
+		return HandlerFunc(func(w *response.Writer, req *request.Request) {
+			encoding := negotiateEncoding(req.Headers.Values("accept-encoding"))
+
+			w.SetBodyEncoder(func(h *headers.Headers, dst io.Writer) io.WriteCloser {
+				if h.Has("content-encoding") || h.Has("content-range") || !compressible(h.Get("content-type")) {
+					return nil
+				}
+
+				if !h.HasToken("vary", "accept-encoding") {
+					h.Add("vary", "Accept-Encoding")
+				}
+
+				if encoding == "" {
+					return nil
+				}
+
+				if n, err := strconv.Atoi(h.Get("content-length")); err == nil && n < minSize {
+					return nil
+				}
+
+				h.Set("content-encoding", encoding)
+				if etag := h.Get("etag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+					h.Set("etag", "W/"+etag)
+				}
+
+				if encoding == "gzip" {
+					return gzip.NewWriter(dst)
+				}
+				return zlib.NewWriter(dst)
+			})
+
+			next.ServeHTTP(w, req)
+		})
+	}
+}
+
+func negotiateEncoding(values []string) string {
+	best := ""
+	bestQ := 0.0
+	wildcardQ := -1.0
+	seen := make(map[string]bool)
+
+	for _, value := range values {
+		for _, part := range strings.Split(value, ",") {
+			coding, q, ok := parseQValue(part)
+			if !ok {
+				continue
+			}
+
+			if coding == "*" {
+				wildcardQ = q
+				continue
+			}
+
+			if coding != "gzip" && coding != "deflate" {
+				continue
+			}
+
+			seen[coding] = true
+			if q > bestQ || (q == bestQ && q > 0 && coding == "gzip") {
+				best, bestQ = coding, q
+			}
+		}
+	}
+
+	if wildcardQ > bestQ {
+		for _, coding := range []string{"gzip", "deflate"} {
+			if !seen[coding] {
+				return coding
+			}
+		}
+	}
+
+	return best
+}
+
+func parseQValue(part string) (string, float64, bool) {
+	coding, params, _ := strings.Cut(part, ";")
+	coding = strings.ToLower(strings.TrimSpace(coding))
+	if coding == "" {
+		return "", 0, false
+	}
+
+	q := 1.0
+	for _, param := range strings.Split(params, ";") {
+		name, value, found := strings.Cut(strings.TrimSpace(param), "=")
+		if !found || !strings.EqualFold(strings.TrimSpace(name), "q") {
+			continue
+		}
+
+		parsed, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil || parsed < 0 || parsed > 1 {
+			return "", 0, false
+		}
+		q = parsed
+	}
+
+	return coding, q, true
+}
+
+func compressible(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+
+	return strings.HasPrefix(mediaType, "text/") ||
+		strings.HasSuffix(mediaType, "+json") ||
+		strings.HasSuffix(mediaType, "+xml") ||
+		slices.Contains(compressibleTypes, mediaType)
+}
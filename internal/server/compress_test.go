@@ -0,0 +1,166 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"surya.httpfromtcp/internal/request"
+	"surya.httpfromtcp/internal/response"
+)
+
+func readChunkedBody(t *testing.T, reader *bufio.Reader) []byte {
+	t.Helper()
+
+	var body []byte
+	for {
+		line, err := reader.ReadString('\n')
+		require.NoError(t, err)
+		size, err := strconv.ParseInt(strings.TrimSpace(line), 16, 64)
+		require.NoError(t, err)
+
+		if size == 0 {
+			line, err = reader.ReadString('\n')
+			require.NoError(t, err)
+			require.Equal(t, "\r\n", line)
+			return body
+		}
+
+		chunk := make([]byte, size+2)
+		_, err = io.ReadFull(reader, chunk)
+		require.NoError(t, err)
+		body = append(body, chunk[:size]...)
+	}
+}
+
+func contentHandler(contentType, body string) HandlerFunc {
+	return func(w *response.Writer, req *request.Request) {
+		h := response.GetDefaultHeaders(len(body))
+		h.Set("content-type", contentType)
+		w.WriteStatusLine(response.StatusOK)
+		w.WriteHeaders(h)
+		w.WriteBody([]byte(body))
+	}
+}
+
+func TestNegotiateEncoding(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{"", ""},
+		{"gzip", "gzip"},
+		{"deflate", "deflate"},
+		{"deflate, gzip", "gzip"},
+		{"gzip;q=0.5, deflate", "deflate"},
+		{"gzip;q=0, deflate;q=0", ""},
+		{"GZIP;Q=0.8, br", "gzip"},
+		{"br, identity", ""},
+		{"*", "gzip"},
+		{"gzip;q=0, *;q=0.5", "deflate"},
+		{"*;q=0", ""},
+		{"gzip;q=2", ""},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, negotiateEncoding([]string{tt.header}), tt.header)
+	}
+}
+
+func TestCompress(t *testing.T) {
+	large := strings.Repeat("compress me please ", 200)
+	router := NewRouter()
+	router.Handle("/large", contentHandler("text/html; charset=utf-8", large))
+	router.Handle("/small", contentHandler("application/json", "{}"))
+	router.Handle("/image", contentHandler("image/png", large))
+	router.Handle("/chunked", HandlerFunc(func(w *response.Writer, req *request.Request) {
+		h := response.GetDefaultHeaders(0)
+		h.Del("content-length")
+		h.Set("transfer-encoding", "chunked")
+		w.WriteStatusLine(response.StatusOK)
+		w.WriteHeaders(h)
+		w.WriteChunkedBody([]byte("streamed "))
+		w.WriteChunkedBody([]byte("text"))
+	}))
+
+	conn := startServer(t, Chain(router, Compress(DefaultCompressMinSize)))
+	reader := bufio.NewReader(conn)
+
+	_, err := conn.Write([]byte("GET /large HTTP/1.1\r\nHost: localhost\r\nAccept-Encoding: gzip, deflate\r\n\r\n"))
+	require.NoError(t, err)
+	head, _ := readResponse(t, reader)
+	assert.Contains(t, head, "content-encoding: gzip\r\n")
+	assert.Contains(t, head, "transfer-encoding: chunked\r\n")
+	assert.Contains(t, head, "vary: Accept-Encoding\r\n")
+	assert.NotContains(t, head, "content-length")
+
+	compressed := readChunkedBody(t, reader)
+	assert.Less(t, len(compressed), len(large))
+	gz, err := gzip.NewReader(bytes.NewReader(compressed))
+	require.NoError(t, err)
+	decoded, err := io.ReadAll(gz)
+	require.NoError(t, err)
+	assert.Equal(t, large, string(decoded))
+
+	_, err = conn.Write([]byte("GET /chunked HTTP/1.1\r\nHost: localhost\r\nAccept-Encoding: gzip;q=0.1, deflate;q=0.9\r\n\r\n"))
+	require.NoError(t, err)
+	head, _ = readResponse(t, reader)
+	assert.Contains(t, head, "content-encoding: deflate\r\n")
+	zr, err := zlib.NewReader(bytes.NewReader(readChunkedBody(t, reader)))
+	require.NoError(t, err)
+	decoded, err = io.ReadAll(zr)
+	require.NoError(t, err)
+	assert.Equal(t, "streamed text", string(decoded))
+
+	_, err = conn.Write([]byte("GET /small HTTP/1.1\r\nHost: localhost\r\nAccept-Encoding: gzip\r\n\r\n"))
+	require.NoError(t, err)
+	head, body := readResponse(t, reader)
+	assert.NotContains(t, head, "content-encoding")
+	assert.Contains(t, head, "vary: Accept-Encoding\r\n")
+	assert.Equal(t, "{}", body)
+
+	_, err = conn.Write([]byte("GET /image HTTP/1.1\r\nHost: localhost\r\nAccept-Encoding: gzip\r\n\r\n"))
+	require.NoError(t, err)
+	head, body = readResponse(t, reader)
+	assert.NotContains(t, head, "content-encoding")
+	assert.NotContains(t, head, "vary")
+	assert.Equal(t, large, body)
+
+	_, err = conn.Write([]byte("GET /large HTTP/1.1\r\nHost: localhost\r\n\r\n"))
+	require.NoError(t, err)
+	head, body = readResponse(t, reader)
+	assert.NotContains(t, head, "content-encoding")
+	assert.Contains(t, head, "vary: Accept-Encoding\r\n")
+	assert.Equal(t, large, body)
+}
+
+func TestCompressHTTP10(t *testing.T) {
+	large := strings.Repeat("compress me please ", 200)
+	conn := startServer(t, Chain(contentHandler("text/plain", large), Compress(DefaultCompressMinSize)))
+
+	_, err := conn.Write([]byte("GET / HTTP/1.0\r\nAccept-Encoding: gzip\r\n\r\n"))
+	require.NoError(t, err)
+
+	out, err := io.ReadAll(conn)
+	require.NoError(t, err)
+	head, body, ok := strings.Cut(string(out), "\r\n\r\n")
+	head += "\r\n"
+	require.True(t, ok)
+	assert.Contains(t, head, "content-encoding: gzip\r\n")
+	assert.Contains(t, head, "connection: close")
+	assert.NotContains(t, head, "transfer-encoding")
+
+	gz, err := gzip.NewReader(strings.NewReader(body))
+	require.NoError(t, err)
+	decoded, err := io.ReadAll(gz)
+	require.NoError(t, err)
+	assert.Equal(t, large, string(decoded))
+}
@@ -0,0 +1,256 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"surya.httpfromtcp/internal/headers"
+	"surya.httpfromtcp/internal/request"
+	"surya.httpfromtcp/internal/response"
+)
+
+const maxRanges = 32
+
+var (
+	ErrInvalidRange       = errors.New("invalid range")
+	ErrUnsatisfiableRange = errors.New("unsatisfiable range")
+)
+
+type ByteRange struct {
+	Start  int64
+	Length int64
+}
+
+func (ra ByteRange) ContentRange(size int64) string {
+	return fmt.Sprintf("bytes %d-%d/%d", ra.Start, ra.Start+ra.Length-1, size)
+}
+
+// An etag or content-type already set on w.Header() is used as is;
+// otherwise both are derived from modTime, the content size and name.
+func ServeContent(w *response.Writer, req *request.Request, name string, modTime time.Time, content io.ReadSeeker) {
+	size, err := content.Seek(0, io.SeekEnd)
+	if err == nil {
+		_, err = content.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		writeError(w, response.StatusInternalServerError, "internal server error", nil)
+		return
+	}
+
+	h := headers.NewHeaders()
+	etag := w.Header().Get("etag")
+	if etag == "" && !modTime.IsZero() {
+		etag = fmt.Sprintf("\"%x-%x\"", modTime.UnixNano(), size)
+		h.Set("etag", etag)
+	}
+	if !modTime.IsZero() {
+		h.Set("last-modified", modTime.UTC().Format(response.TimeFormat))
+	}
+	h.Set("accept-ranges", "bytes")
+
+	if notModified(req, etag, modTime) {
+		w.WriteStatusLine(response.StatusNotModified)
+		w.WriteHeaders(h)
+		return
+	}
+
+	contentType := w.Header().Get("content-type")
+	if contentType == "" {
+		contentType = mime.TypeByExtension(path.Ext(name))
+	}
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	var ranges []ByteRange
+	if rangeApplies(req, etag, modTime) {
+		ranges, err = ParseRange(req.Headers.Get("range"), size)
+	}
+	if errors.Is(err, ErrUnsatisfiableRange) {
+		h.Set("content-range", fmt.Sprintf("bytes */%d", size))
+		writeError(w, response.StatusRangeNotSatisfiable, "range not satisfiable", h)
+		return
+	}
+
+	switch {
+	case err != nil || len(ranges) == 0:
+		h.Set("content-type", contentType)
+		h.Set("content-length", strconv.FormatInt(size, 10))
+		w.WriteStatusLine(response.StatusOK)
+		w.WriteHeaders(h)
+		io.CopyN(bodyWriter{w}, content, size)
+
+	case len(ranges) == 1:
+		ra := ranges[0]
+		if _, err := content.Seek(ra.Start, io.SeekStart); err != nil {
+			writeError(w, response.StatusInternalServerError, "internal server error", nil)
+			return
+		}
+
+		h.Set("content-type", contentType)
+		h.Set("content-range", ra.ContentRange(size))
+		h.Set("content-length", strconv.FormatInt(ra.Length, 10))
+		w.WriteStatusLine(response.StatusPartialContent)
+		w.WriteHeaders(h)
+		io.CopyN(bodyWriter{w}, content, ra.Length)
+
+	default:
+		boundary := randomHex(16)
+		partHeaders := make([]string, len(ranges))
+		length := int64(len("\r\n--" + boundary + "--\r\n"))
+		for i, ra := range ranges {
+			partHeaders[i] = fmt.Sprintf("\r\n--%s\r\ncontent-type: %s\r\ncontent-range: %s\r\n\r\n", boundary, contentType, ra.ContentRange(size))
+			length += int64(len(partHeaders[i])) + ra.Length
+		}
+
+		h.Set("content-type", "multipart/byteranges; boundary="+boundary)
+		h.Set("content-length", strconv.FormatInt(length, 10))
+		w.WriteStatusLine(response.StatusPartialContent)
+		w.WriteHeaders(h)
+
+		for i, ra := range ranges {
+			if _, err := content.Seek(ra.Start, io.SeekStart); err != nil {
+				return
+			}
+			w.WriteBody([]byte(partHeaders[i]))
+			if _, err := io.CopyN(bodyWriter{w}, content, ra.Length); err != nil {
+				return
+			}
+		}
+		w.WriteBody([]byte("\r\n--" + boundary + "--\r\n"))
+	}
+}
+
+func ParseRange(header string, size int64) ([]ByteRange, error) {
+	if header == "" {
+		return nil, nil
+	}
+
+	spec, ok := strings.CutPrefix(header, "bytes=")
+	if !ok {
+		return nil, ErrInvalidRange
+	}
+
+	var ranges []ByteRange
+	specs := 0
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		specs++
+
+		first, last, ok := strings.Cut(part, "-")
+		if !ok {
+			return nil, ErrInvalidRange
+		}
+
+		if first == "" {
+			n, err := parseRangeInt(last)
+			if err != nil {
+				return nil, err
+			}
+			n = min(n, size)
+			if n > 0 {
+				ranges = append(ranges, ByteRange{Start: size - n, Length: n})
+			}
+			continue
+		}
+
+		start, err := parseRangeInt(first)
+		if err != nil {
+			return nil, err
+		}
+
+		end := size - 1
+		if last != "" {
+			e, err := parseRangeInt(last)
+			if err != nil || e < start {
+				return nil, ErrInvalidRange
+			}
+			end = min(e, size-1)
+		}
+
+		if start < size {
+			ranges = append(ranges, ByteRange{Start: start, Length: end - start + 1})
+		}
+	}
+
+	switch {
+	case specs == 0 || specs > maxRanges:
+		return nil, ErrInvalidRange
+	case len(ranges) == 0:
+		return nil, ErrUnsatisfiableRange
+	default:
+		return ranges, nil
+	}
+}
+
+func parseRangeInt(s string) (int64, error) {
+	if s == "" || strings.Trim(s, "0123456789") != "" {
+		return 0, ErrInvalidRange
+	}
+
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, ErrInvalidRange
+	}
+	return n, nil
+}
+
+func notModified(req *request.Request, etag string, modTime time.Time) bool {
+	if req.Headers.Has("if-none-match") {
+		if etag == "" {
+			return false
+		}
+
+		for _, value := range req.Headers.Values("if-none-match") {
+			for _, candidate := range strings.Split(value, ",") {
+				candidate = strings.TrimSpace(candidate)
+				if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+					return true
+				}
+			}
+		}
+		return false
+	}
+
+	since, err := time.Parse(response.TimeFormat, req.Headers.Get("if-modified-since"))
+	if err != nil || modTime.IsZero() {
+		return false
+	}
+
+	return !modTime.Truncate(time.Second).After(since)
+}
+
+func rangeApplies(req *request.Request, etag string, modTime time.Time) bool {
+	ifRange := req.Headers.Get("if-range")
+	if ifRange == "" {
+		return true
+	}
+
+	if strings.HasPrefix(ifRange, "\"") {
+		return etag != "" && !strings.HasPrefix(etag, "W/") && ifRange == etag
+	}
+
+	date, err := time.Parse(response.TimeFormat, ifRange)
+	if err != nil || modTime.IsZero() {
+		return false
+	}
+
+	return modTime.Truncate(time.Second).Equal(date)
+}
+
+type bodyWriter struct {
+	w *response.Writer
+}
+
+func (b bodyWriter) Write(p []byte) (int, error) {
+	return b.w.WriteBody(p)
+}
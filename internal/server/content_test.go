@@ -0,0 +1,113 @@
+package server
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"surya.httpfromtcp/internal/request"
+	"surya.httpfromtcp/internal/response"
+)
+
+func TestParseRange(t *testing.T) {
+	tests := []struct {
+		header string
+		ranges []ByteRange
+		err    error
+	}{
+		{"", nil, nil},
+		{"bytes=0-99", []ByteRange{{0, 100}}, nil},
+		{"bytes=900-", []ByteRange{{900, 100}}, nil},
+		{"bytes=-50", []ByteRange{{950, 50}}, nil},
+		{"bytes=-5000", []ByteRange{{0, 1000}}, nil},
+		{"bytes=990-2000", []ByteRange{{990, 10}}, nil},
+		{"bytes=0-0, 10-19 ,-1", []ByteRange{{0, 1}, {10, 10}, {999, 1}}, nil},
+		{"bytes=0-1,5000-", []ByteRange{{0, 2}}, nil},
+		{"bytes=1000-", nil, ErrUnsatisfiableRange},
+		{"bytes=-0", nil, ErrUnsatisfiableRange},
+		{"bytes=", nil, ErrInvalidRange},
+		{"bytes=10-5", nil, ErrInvalidRange},
+		{"bytes=a-5", nil, ErrInvalidRange},
+		{"bytes=+1-5", nil, ErrInvalidRange},
+		{"bytes=5", nil, ErrInvalidRange},
+		{"lines=0-5", nil, ErrInvalidRange},
+		{"bytes=" + strings.Repeat("0-0,", maxRanges+1), nil, ErrInvalidRange},
+	}
+
+	for _, tt := range tests {
+		ranges, err := ParseRange(tt.header, 1000)
+		if tt.err != nil {
+			assert.ErrorIs(t, err, tt.err, tt.header)
+			continue
+		}
+		require.NoError(t, err, tt.header)
+		assert.Equal(t, tt.ranges, ranges, tt.header)
+	}
+}
+
+func serveBlob(t *testing.T, extra string, setup func(w *response.Writer)) string {
+	t.Helper()
+
+	req, err := request.RequestFromReader(strings.NewReader("GET /blob HTTP/1.1\r\nHost: localhost\r\n" + extra + "\r\n"))
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	w := response.NewWriter(&buf)
+	if setup != nil {
+		setup(w)
+	}
+	ServeContent(w, req, "blob.bin", time.Time{}, strings.NewReader("0123456789"))
+	return buf.String()
+}
+
+func TestServeContent(t *testing.T) {
+	out := serveBlob(t, "", nil)
+	assert.True(t, strings.HasPrefix(out, "HTTP/1.1 200 OK\r\n"), out)
+	assert.Contains(t, out, "content-type: application/octet-stream\r\n")
+	assert.NotContains(t, out, "etag:")
+	assert.NotContains(t, out, "last-modified:")
+	assert.True(t, strings.HasSuffix(out, "\r\n\r\n0123456789"))
+
+	withETag := func(w *response.Writer) {
+		w.Header().Set("etag", "\"v1\"")
+		w.Header().Set("content-type", "video/mp4")
+	}
+
+	out = serveBlob(t, "Range: bytes=2-5\r\n", withETag)
+	assert.True(t, strings.HasPrefix(out, "HTTP/1.1 206 Partial Content\r\n"), out)
+	assert.Contains(t, out, "etag: \"v1\"\r\n")
+	assert.Contains(t, out, "content-type: video/mp4\r\n")
+	assert.Contains(t, out, "content-range: bytes 2-5/10\r\n")
+	assert.True(t, strings.HasSuffix(out, "\r\n\r\n2345"))
+
+	out = serveBlob(t, "Range: bytes=2-5\r\nIf-Range: \"v1\"\r\n", withETag)
+	assert.True(t, strings.HasPrefix(out, "HTTP/1.1 206 Partial Content\r\n"), out)
+
+	out = serveBlob(t, "Range: bytes=2-5\r\nIf-Range: \"v0\"\r\n", withETag)
+	assert.True(t, strings.HasPrefix(out, "HTTP/1.1 200 OK\r\n"), out)
+	assert.True(t, strings.HasSuffix(out, "\r\n\r\n0123456789"))
+
+	out = serveBlob(t, "Range: bytes=2-5\r\nIf-Range: Fri, 01 Mar 2024 12:00:00 GMT\r\n", withETag)
+	assert.True(t, strings.HasPrefix(out, "HTTP/1.1 200 OK\r\n"), out)
+
+	out = serveBlob(t, "If-None-Match: \"v1\"\r\n", withETag)
+	assert.True(t, strings.HasPrefix(out, "HTTP/1.1 304 Not Modified\r\n"), out)
+
+	out = serveBlob(t, "Range: bytes=10-\r\n", nil)
+	assert.True(t, strings.HasPrefix(out, "HTTP/1.1 416 Range Not Satisfiable\r\n"), out)
+	assert.Contains(t, out, "content-range: bytes */10\r\n")
+}
+
+func TestFileServerIfRange(t *testing.T) {
+	fsrv := NewFileServer("/", setupFileRoot(t))
+
+	out := serveHandler(t, fsrv, fileRequest("/hello.txt", "Range: bytes=0-4\r\nIf-Range: Fri, 01 Mar 2024 12:00:00 GMT\r\n"))
+	assert.True(t, strings.HasPrefix(out, "HTTP/1.1 206 Partial Content\r\n"), out)
+
+	out = serveHandler(t, fsrv, fileRequest("/hello.txt", "Range: bytes=0-4\r\nIf-Range: Thu, 29 Feb 2024 12:00:00 GMT\r\n"))
+	assert.True(t, strings.HasPrefix(out, "HTTP/1.1 200 OK\r\n"), out)
+}
@@ -0,0 +1,24 @@
+package server
+
+import (
+	"io"
+
+	"surya.httpfromtcp/internal/response"
+)
+
+type continueBody struct {
+	io.ReadCloser
+	w    *response.Writer
+	sent bool
+}
+
+func (b *continueBody) Read(p []byte) (int, error) {
+	if !b.sent && !b.w.Written() {
+		if err := b.w.WriteInformational(response.StatusContinue, nil); err != nil {
+			return 0, err
+		}
+		b.sent = true
+	}
+
+	return b.ReadCloser.Read(p)
+}
@@ -0,0 +1,25 @@
+package server
+
+import (
+	"sync"
+	"time"
+
+	"surya.httpfromtcp/internal/response"
+)
+
+type dateCache struct {
+	mu     sync.Mutex
+	second int64
+	value  string
+}
+
+func (c *dateCache) get(now time.Time) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if sec := now.Unix(); sec != c.second || c.value == "" {
+		c.second = sec
+		c.value = now.UTC().Format(response.TimeFormat)
+	}
+	return c.value
+}
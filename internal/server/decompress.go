@@ -0,0 +1,87 @@
+package server
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"strings"
+
+	"surya.httpfromtcp/internal/headers"
+	"surya.httpfromtcp/internal/request"
+	"surya.httpfromtcp/internal/response"
+)
+
+const DefaultMaxDecodedBodyBytes = 10 << 20
+
+func Decompress(maxSize int) Middleware {
+	return func(next Handler) Handler {
+		return HandlerFunc(func(w *response.Writer, req *request.Request) {
+			var codings []string
+			for _, value := range req.Headers.Values("content-encoding") {
+				for _, coding := range strings.Split(value, ",") {
+					coding = strings.ToLower(strings.TrimSpace(coding))
+					switch coding {
+					case "", "identity":
+					case "gzip", "x-gzip", "deflate":
+						codings = append(codings, coding)
+					default:
+						h := headers.NewHeaders()
+						h.Set("accept-encoding", "gzip, deflate")
+						writeError(w, response.StatusUnsupportedMediaType, "unsupported content encoding: "+coding, h)
+						return
+					}
+				}
+			}
+
+			if len(codings) > 0 {
+				req.Body = &decodedBody{raw: req.Body, codings: codings, limit: maxSize}
+				req.Headers.Del("content-encoding")
+				req.Headers.Del("content-length")
+			}
+
+			next.ServeHTTP(w, req)
+		})
+	}
+}
+
+type decodedBody struct {
+	raw     io.ReadCloser
+	codings []string
+	limit   int
+	reader  io.Reader
+	read    int
+}
+
+func (b *decodedBody) Read(p []byte) (int, error) {
+	if b.reader == nil {
+		r := io.Reader(b.raw)
+		for i := len(b.codings) - 1; i >= 0; i-- {
+			var err error
+			if b.codings[i] == "deflate" {
+				r, err = zlib.NewReader(r)
+			} else {
+				r, err = gzip.NewReader(r)
+			}
+			if err != nil {
+				return 0, fmt.Errorf("invalid %s body: %w", b.codings[i], err)
+			}
+		}
+		b.reader = r
+	}
+
+	if b.limit > 0 && len(p) > b.limit-b.read+1 {
+		p = p[:b.limit-b.read+1]
+	}
+
+	n, err := b.reader.Read(p)
+	b.read += n
+	if b.limit > 0 && b.read > b.limit {
+		return 0, fmt.Errorf("%w: decoded body exceeds %d bytes", request.ErrBodyTooLarge, b.limit)
+	}
+	return n, err
+}
+
+func (b *decodedBody) Close() error {
+	return b.raw.Close()
+}
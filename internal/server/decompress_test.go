@@ -0,0 +1,122 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"surya.httpfromtcp/internal/request"
+	"surya.httpfromtcp/internal/response"
+)
+
+func gzipBytes(t *testing.T, data []byte) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	_, err := gz.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, gz.Close())
+	return buf.Bytes()
+}
+
+func zlibBytes(t *testing.T, data []byte) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	zw := zlib.NewWriter(&buf)
+	_, err := zw.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+	return buf.Bytes()
+}
+
+func echoDecoded(w *response.Writer, req *request.Request) {
+	body, err := req.ReadBody()
+	switch {
+	case errors.Is(err, request.ErrBodyTooLarge):
+		writeError(w, response.StatusContentTooLarge, err.Error(), nil)
+	case err != nil:
+		writeError(w, response.StatusBadRequest, err.Error(), nil)
+	default:
+		body = append(body, " encoding="+req.Headers.Get("content-encoding")...)
+		w.WriteStatusLine(response.StatusOK)
+		w.WriteHeaders(response.GetDefaultHeaders(len(body)))
+		w.WriteBody(body)
+	}
+}
+
+func postEncoded(encoding string, body []byte) []byte {
+	head := "POST / HTTP/1.1\r\nHost: localhost\r\n"
+	if encoding != "" {
+		head += "Content-Encoding: " + encoding + "\r\n"
+	}
+	head += "Content-Length: " + strconv.Itoa(len(body)) + "\r\n\r\n"
+	return append([]byte(head), body...)
+}
+
+func TestDecompress(t *testing.T) {
+	conn := startServer(t, Chain(HandlerFunc(echoDecoded), Decompress(1024)))
+	reader := bufio.NewReader(conn)
+
+	tests := []struct {
+		encoding string
+		body     []byte
+		status   string
+		want     string
+	}{
+		{"gzip", gzipBytes(t, []byte("hello gzip")), "200 OK", "hello gzip encoding="},
+		{"deflate", zlibBytes(t, []byte("hello deflate")), "200 OK", "hello deflate encoding="},
+		{"deflate, gzip", gzipBytes(t, zlibBytes(t, []byte("stacked"))), "200 OK", "stacked encoding="},
+		{"identity", []byte("plain"), "200 OK", "plain encoding=identity"},
+		{"", []byte("plain"), "200 OK", "plain encoding="},
+		{"gzip", []byte("not gzip at all"), "400 Bad Request", ""},
+		{"gzip", gzipBytes(t, bytes.Repeat([]byte{0}, 1<<20)), "413 Content Too Large", ""},
+		{"gzip", gzipBytes(t, bytes.Repeat([]byte{'a'}, 1024)), "200 OK", strings.Repeat("a", 1024) + " encoding="},
+	}
+
+	for _, tt := range tests {
+		_, err := conn.Write(postEncoded(tt.encoding, tt.body))
+		require.NoError(t, err)
+		head, body := readResponse(t, reader)
+		assert.True(t, strings.HasPrefix(head, "HTTP/1.1 "+tt.status+"\r\n"), tt.encoding+": "+head)
+		if tt.want != "" {
+			assert.Equal(t, tt.want, body)
+		}
+	}
+
+	_, err := conn.Write(postEncoded("br", []byte("brotli")))
+	require.NoError(t, err)
+	head, _ := readResponse(t, reader)
+	assert.True(t, strings.HasPrefix(head, "HTTP/1.1 415 Unsupported Media Type\r\n"), head)
+	assert.Contains(t, head, "accept-encoding: gzip, deflate\r\n")
+
+	_, err = conn.Write(postEncoded("", []byte("still alive")))
+	require.NoError(t, err)
+	_, body := readResponse(t, reader)
+	assert.Equal(t, "still alive encoding=", body)
+}
+
+func TestDecompressExpectContinueUnreadBody(t *testing.T) {
+	conn := startServer(t, Chain(HandlerFunc(echoTarget), Decompress(1024)))
+	reader := bufio.NewReader(conn)
+
+	_, err := conn.Write([]byte("POST /skip HTTP/1.1\r\nHost: localhost\r\nExpect: 100-continue\r\nContent-Encoding: gzip\r\nContent-Length: 20\r\n\r\n"))
+	require.NoError(t, err)
+	head, body := readResponse(t, reader)
+	assert.True(t, strings.HasPrefix(head, "HTTP/1.1 200 OK\r\n"), head)
+	assert.Contains(t, head, "connection: close\r\n")
+	assert.Equal(t, "/skip", body)
+
+	_, err = reader.ReadByte()
+	assert.ErrorIs(t, err, io.EOF)
+}
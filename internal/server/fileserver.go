@@ -0,0 +1,177 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"io/fs"
+	"net/url"
+	"os"
+	"path"
+	"slices"
+	"strings"
+
+	"surya.httpfromtcp/internal/headers"
+	"surya.httpfromtcp/internal/request"
+	"surya.httpfromtcp/internal/response"
+)
+
+const indexFile = "index.html"
+
+type FileServer struct {
+	prefix   string
+	root     string
+	listDirs bool
+}
+
+type FileServerOption func(*FileServer)
+
+func WithDirectoryListing() FileServerOption {
+	return func(fsrv *FileServer) {
+		fsrv.listDirs = true
+	}
+}
+
+func NewFileServer(prefix, root string, opts ...FileServerOption) *FileServer {
+	fsrv := &FileServer{
+		prefix: strings.TrimSuffix(prefix, "/"),
+		root:   root,
+	}
+
+	for _, opt := range opts {
+		opt(fsrv)
+	}
+
+	return fsrv
+}
+
+func (fsrv *FileServer) ServeHTTP(w *response.Writer, req *request.Request) {
+	method := req.RequestLine.Method
+	if method != "GET" && method != "HEAD" {
+		h := headers.NewHeaders()
+		h.Set("allow", "GET, HEAD")
+		writeError(w, response.StatusMethodNotAllowed, "method not allowed", h)
+		return
+	}
+
+	rel, ok := strings.CutPrefix(req.Path(), fsrv.prefix)
+	if !ok || (rel != "" && !strings.HasPrefix(rel, "/")) {
+		writeError(w, response.StatusNotFound, "not found", nil)
+		return
+	}
+
+	if !validFilePath(rel) {
+		writeError(w, response.StatusBadRequest, "invalid path", nil)
+		return
+	}
+
+	root, err := os.OpenRoot(fsrv.root)
+	if err != nil {
+		writeError(w, response.StatusInternalServerError, "internal server error", nil)
+		return
+	}
+	defer root.Close()
+
+	name := strings.TrimPrefix(path.Clean("/"+rel), "/")
+	if name == "" {
+		name = "."
+	}
+
+	f, err := root.Open(name)
+	if err != nil {
+		writeFileError(w, err)
+		return
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		writeFileError(w, err)
+		return
+	}
+
+	if !info.IsDir() {
+		ServeContent(w, req, info.Name(), info.ModTime(), f)
+		return
+	}
+
+	if !strings.HasSuffix(req.RawPath(), "/") {
+		location := req.RawPath() + "/"
+		if req.RawQuery() != "" {
+			location += "?" + req.RawQuery()
+		}
+		h := headers.NewHeaders()
+		h.Set("location", location)
+		writeError(w, response.StatusMovedPermanently, "moved permanently", h)
+		return
+	}
+
+	if index, err := root.Open(path.Join(name, indexFile)); err == nil {
+		defer index.Close()
+		if indexInfo, err := index.Stat(); err == nil && !indexInfo.IsDir() {
+			ServeContent(w, req, indexFile, indexInfo.ModTime(), index)
+			return
+		}
+	}
+
+	if !fsrv.listDirs {
+		writeError(w, response.StatusForbidden, "forbidden", nil)
+		return
+	}
+
+	serveDirectory(w, req, f)
+}
+
+func validFilePath(rel string) bool {
+	if strings.ContainsAny(rel, "\x00\\") {
+		return false
+	}
+
+	for _, seg := range strings.Split(rel, "/") {
+		if seg == ".." {
+			return false
+		}
+	}
+
+	return true
+}
+
+func writeFileError(w *response.Writer, err error) {
+	if errors.Is(err, fs.ErrPermission) {
+		writeError(w, response.StatusForbidden, "forbidden", nil)
+		return
+	}
+	writeError(w, response.StatusNotFound, "not found", nil)
+}
+
+func serveDirectory(w *response.Writer, req *request.Request, dir *os.File) {
+	entries, err := dir.ReadDir(-1)
+	if err != nil {
+		writeError(w, response.StatusInternalServerError, "internal server error", nil)
+		return
+	}
+
+	slices.SortFunc(entries, func(a, b os.DirEntry) int {
+		return strings.Compare(a.Name(), b.Name())
+	})
+
+	title := html.EscapeString(req.Path())
+	var b strings.Builder
+	fmt.Fprintf(&b, "<html>\n  <head>\n    <title>Index of %s</title>\n  </head>\n  <body>\n    <h1>Index of %s</h1>\n    <ul>\n", title, title)
+	b.WriteString("      <li><a href=\"../\">../</a></li>\n")
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() {
+			name += "/"
+		}
+		fmt.Fprintf(&b, "      <li><a href=\"%s\">%s</a></li>\n", html.EscapeString((&url.URL{Path: name}).EscapedPath()), html.EscapeString(name))
+	}
+	b.WriteString("    </ul>\n  </body>\n</html>\n")
+
+	body := []byte(b.String())
+	h := response.GetDefaultHeaders(len(body))
+	h.Set("content-type", "text/html; charset=utf-8")
+	w.WriteStatusLine(response.StatusOK)
+	w.WriteHeaders(h)
+	w.WriteBody(body)
+}
@@ -0,0 +1,179 @@
+package server
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupFileRoot(t *testing.T) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	root := filepath.Join(dir, "root")
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "site"), 0o755))
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "docs"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "hello.txt"), []byte("hello, world"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "site", "index.html"), []byte("<h1>site</h1>"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "docs", "a & b.txt"), []byte("a"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "secret.txt"), []byte("secret"), 0o644))
+	require.NoError(t, os.Symlink(filepath.Join(dir, "secret.txt"), filepath.Join(root, "escape.txt")))
+
+	modTime := time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+	require.NoError(t, os.Chtimes(filepath.Join(root, "hello.txt"), modTime, modTime))
+
+	return root
+}
+
+func fileRequest(target string, extra ...string) string {
+	return "GET " + target + " HTTP/1.1\r\nHost: localhost\r\n" + strings.Join(extra, "") + "\r\n"
+}
+
+func TestFileServer(t *testing.T) {
+	fsrv := NewFileServer("/static/", setupFileRoot(t))
+
+	out := serveHandler(t, fsrv, fileRequest("/static/hello.txt"))
+	assert.True(t, strings.HasPrefix(out, "HTTP/1.1 200 OK\r\n"), out)
+	assert.Contains(t, out, "content-type: text/plain; charset=utf-8\r\n")
+	assert.Contains(t, out, "content-length: 12\r\n")
+	assert.Contains(t, out, "last-modified: Fri, 01 Mar 2024 12:00:00 GMT\r\n")
+	assert.Contains(t, out, "accept-ranges: bytes\r\n")
+	assert.True(t, strings.HasSuffix(out, "\r\n\r\nhello, world"))
+
+	etag := ""
+	for _, line := range strings.Split(out, "\r\n") {
+		if value, ok := strings.CutPrefix(line, "etag: "); ok {
+			etag = value
+		}
+	}
+	require.NotEmpty(t, etag)
+
+	out = serveHandler(t, fsrv, fileRequest("/static/hello.txt", "If-None-Match: \"other\", "+etag+"\r\n"))
+	assert.True(t, strings.HasPrefix(out, "HTTP/1.1 304 Not Modified\r\n"), out)
+	assert.True(t, strings.HasSuffix(out, "\r\n\r\n"))
+
+	out = serveHandler(t, fsrv, fileRequest("/static/hello.txt", "If-None-Match: \"other\"\r\nIf-Modified-Since: Sat, 02 Mar 2024 00:00:00 GMT\r\n"))
+	assert.True(t, strings.HasPrefix(out, "HTTP/1.1 200 OK\r\n"), out)
+
+	out = serveHandler(t, fsrv, fileRequest("/static/hello.txt", "If-Modified-Since: Fri, 01 Mar 2024 12:00:00 GMT\r\n"))
+	assert.True(t, strings.HasPrefix(out, "HTTP/1.1 304 Not Modified\r\n"), out)
+
+	out = serveHandler(t, fsrv, fileRequest("/static/hello.txt", "If-Modified-Since: Thu, 29 Feb 2024 00:00:00 GMT\r\n"))
+	assert.True(t, strings.HasPrefix(out, "HTTP/1.1 200 OK\r\n"), out)
+
+	out = serveHandler(t, fsrv, fileRequest("/static/site/"))
+	assert.True(t, strings.HasPrefix(out, "HTTP/1.1 200 OK\r\n"), out)
+	assert.Contains(t, out, "content-type: text/html; charset=utf-8\r\n")
+	assert.True(t, strings.HasSuffix(out, "<h1>site</h1>"))
+
+	out = serveHandler(t, fsrv, fileRequest("/static/site?x=1"))
+	assert.True(t, strings.HasPrefix(out, "HTTP/1.1 301 Moved Permanently\r\n"), out)
+	assert.Contains(t, out, "location: /static/site/?x=1\r\n")
+
+	out = serveHandler(t, fsrv, fileRequest("/static/docs/"))
+	assert.True(t, strings.HasPrefix(out, "HTTP/1.1 403 Forbidden\r\n"), out)
+
+	for _, target := range []string{"/static/missing.txt", "/static/escape.txt", "/other/hello.txt", "/statichello.txt"} {
+		out = serveHandler(t, fsrv, fileRequest(target))
+		assert.True(t, strings.HasPrefix(out, "HTTP/1.1 404 Not Found\r\n"), target)
+	}
+
+	for _, target := range []string{"/static/../secret.txt", "/static/%2e%2e/secret.txt", "/static/site/..%2f..%2fsecret.txt", "/static/a%5cb"} {
+		out = serveHandler(t, fsrv, fileRequest(target))
+		assert.True(t, strings.HasPrefix(out, "HTTP/1.1 400 Bad Request\r\n"), target)
+	}
+
+	out = serveHandler(t, fsrv, "POST /static/hello.txt HTTP/1.1\r\nHost: localhost\r\n\r\n")
+	assert.True(t, strings.HasPrefix(out, "HTTP/1.1 405 Method Not Allowed\r\n"), out)
+	assert.Contains(t, out, "allow: GET, HEAD\r\n")
+}
+
+func TestFileServerDirectoryListing(t *testing.T) {
+	fsrv := NewFileServer("/", setupFileRoot(t), WithDirectoryListing())
+
+	out := serveHandler(t, fsrv, fileRequest("/docs/"))
+	assert.True(t, strings.HasPrefix(out, "HTTP/1.1 200 OK\r\n"), out)
+	assert.Contains(t, out, "<title>Index of /docs/</title>")
+	assert.Contains(t, out, "<a href=\"a%20&amp;%20b.txt\">a &amp; b.txt</a>")
+
+	out = serveHandler(t, fsrv, fileRequest("/"))
+	assert.Contains(t, out, "<a href=\"docs/\">docs/</a>")
+	assert.Contains(t, out, "<a href=\"hello.txt\">hello.txt</a>")
+}
+
+func TestFileServerRanges(t *testing.T) {
+	fsrv := NewFileServer("/static", setupFileRoot(t))
+
+	out := serveHandler(t, fsrv, fileRequest("/static/hello.txt", "Range: bytes=0-4\r\n"))
+	assert.True(t, strings.HasPrefix(out, "HTTP/1.1 206 Partial Content\r\n"), out)
+	assert.Contains(t, out, "content-range: bytes 0-4/12\r\n")
+	assert.Contains(t, out, "content-length: 5\r\n")
+	assert.True(t, strings.HasSuffix(out, "\r\n\r\nhello"))
+
+	out = serveHandler(t, fsrv, fileRequest("/static/hello.txt", "Range: bytes=-5\r\n"))
+	assert.Contains(t, out, "content-range: bytes 7-11/12\r\n")
+	assert.True(t, strings.HasSuffix(out, "\r\n\r\nworld"))
+
+	out = serveHandler(t, fsrv, fileRequest("/static/hello.txt", "Range: bytes=7-100\r\n"))
+	assert.Contains(t, out, "content-range: bytes 7-11/12\r\n")
+
+	out = serveHandler(t, fsrv, fileRequest("/static/hello.txt", "Range: bytes=12-\r\n"))
+	assert.True(t, strings.HasPrefix(out, "HTTP/1.1 416 Range Not Satisfiable\r\n"), out)
+	assert.Contains(t, out, "content-range: bytes */12\r\n")
+
+	for _, header := range []string{"items=0-1", "bytes=", "bytes=5-1", "bytes=a-b", "bytes=0-1,x"} {
+		out = serveHandler(t, fsrv, fileRequest("/static/hello.txt", "Range: "+header+"\r\n"))
+		assert.True(t, strings.HasPrefix(out, "HTTP/1.1 200 OK\r\n"), header)
+	}
+
+	out = serveHandler(t, fsrv, fileRequest("/static/hello.txt", "Range: bytes=0-0, 7-11\r\n"))
+	assert.True(t, strings.HasPrefix(out, "HTTP/1.1 206 Partial Content\r\n"), out)
+
+	head, body, ok := strings.Cut(out, "\r\n\r\n")
+	require.True(t, ok)
+	_, boundary, ok := strings.Cut(head, "content-type: multipart/byteranges; boundary=")
+	require.True(t, ok)
+	boundary, _, _ = strings.Cut(boundary, "\r\n")
+
+	assert.Equal(t, "\r\n--"+boundary+"\r\n"+
+		"content-type: text/plain; charset=utf-8\r\n"+
+		"content-range: bytes 0-0/12\r\n\r\n"+
+		"h"+
+		"\r\n--"+boundary+"\r\n"+
+		"content-type: text/plain; charset=utf-8\r\n"+
+		"content-range: bytes 7-11/12\r\n\r\n"+
+		"world"+
+		"\r\n--"+boundary+"--\r\n", body)
+	assert.Contains(t, head+"\r\n", "content-length: "+strconv.Itoa(len(body))+"\r\n")
+}
+
+func TestFileServerHead(t *testing.T) {
+	conn := startServer(t, NewFileServer("/", setupFileRoot(t)))
+	reader := bufio.NewReader(conn)
+
+	_, err := conn.Write([]byte("HEAD /hello.txt HTTP/1.1\r\nHost: localhost\r\n\r\n"))
+	require.NoError(t, err)
+
+	var head strings.Builder
+	for {
+		line, err := reader.ReadString('\n')
+		require.NoError(t, err)
+		head.WriteString(line)
+		if line == "\r\n" {
+			break
+		}
+	}
+	assert.Contains(t, head.String(), "content-length: 12\r\n")
+
+	_, err = conn.Write([]byte("GET /hello.txt HTTP/1.1\r\nHost: localhost\r\n\r\n"))
+	require.NoError(t, err)
+	_, body := readResponse(t, reader)
+	assert.Equal(t, "hello, world", body)
+}
@@ -0,0 +1,86 @@
+package server
+
+import (
+	"log"
+	"runtime/debug"
+	"time"
+
+	"surya.httpfromtcp/internal/headers"
+	"surya.httpfromtcp/internal/request"
+	"surya.httpfromtcp/internal/response"
+)
+
+const requestIDHeader = "x-request-id"
+
+type Middleware func(Handler) Handler
+
+func Chain(h Handler, middlewares ...Middleware) Handler {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		h = middlewares[i](h)
+	}
+	return h
+}
+
+func Recover(logger *log.Logger) Middleware {
+	return func(next Handler) Handler {
+		return HandlerFunc(func(w *response.Writer, req *request.Request) {
+			defer func() {
+				if rec := recover(); rec != nil {
+					logger.Printf("panic serving %s %s: %v\n%s", req.RequestLine.Method, req.RequestLine.RequestTarget, rec, debug.Stack())
+
+					if !w.Written() {
+						h := headers.NewHeaders()
+						h.Set("connection", "close")
+						writeError(w, response.StatusInternalServerError, "internal server error", h)
+					}
+				}
+			}()
+
+			next.ServeHTTP(w, req)
+		})
+	}
+}
+
+func Logger(logger *log.Logger) Middleware {
+	return func(next Handler) Handler {
+		return HandlerFunc(func(w *response.Writer, req *request.Request) {
+			start := time.Now()
+			next.ServeHTTP(w, req)
+
+			prefix := ""
+			if id := req.Headers.Get(requestIDHeader); id != "" {
+				prefix = "[" + id + "] "
+			}
+
+			logger.Printf("%s%s %s %d %dB %s", prefix, req.RequestLine.Method, req.RequestLine.RequestTarget, w.StatusCode(), w.BytesWritten(), time.Since(start))
+		})
+	}
+}
+
+func RequestID() Middleware {
+	return func(next Handler) Handler {
+		return HandlerFunc(func(w *response.Writer, req *request.Request) {
+			id := req.Headers.Get(requestIDHeader)
+			if id == "" {
+				id = randomHex(16)
+				req.Headers.Set(requestIDHeader, id)
+			}
+
+			w.Header().Set(requestIDHeader, id)
+			next.ServeHTTP(w, req)
+		})
+	}
+}
+
+func Timing() Middleware {
+	return func(next Handler) Handler {
+		return HandlerFunc(func(w *response.Writer, req *request.Request) {
+			start := time.Now()
+			w.OnWriteHeaders(func(h *headers.Headers) {
+				h.Set("x-response-time", time.Since(start).String())
+			})
+
+			next.ServeHTTP(w, req)
+		})
+	}
+}
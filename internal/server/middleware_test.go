@@ -0,0 +1,79 @@
+package server
+
+import (
+	"bytes"
+	"log"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"surya.httpfromtcp/internal/request"
+	"surya.httpfromtcp/internal/response"
+)
+
+func serveHandler(t *testing.T, handler Handler, raw string) string {
+	t.Helper()
+
+	req, err := request.RequestFromReader(strings.NewReader(raw))
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	handler.ServeHTTP(response.NewWriter(&buf), req)
+	return buf.String()
+}
+
+func TestChainOrder(t *testing.T) {
+	var order []string
+	tag := func(name string) Middleware {
+		return func(next Handler) Handler {
+			return HandlerFunc(func(w *response.Writer, req *request.Request) {
+				order = append(order, name)
+				next.ServeHTTP(w, req)
+			})
+		}
+	}
+
+	handler := Chain(HandlerFunc(func(w *response.Writer, req *request.Request) {
+		order = append(order, "handler")
+	}), tag("first"), tag("second"))
+
+	serveHandler(t, handler, "GET / HTTP/1.1\r\n\r\n")
+	assert.Equal(t, []string{"first", "second", "handler"}, order)
+}
+
+func TestRecover(t *testing.T) {
+	var logs bytes.Buffer
+	logger := log.New(&logs, "", 0)
+
+	handler := Chain(HandlerFunc(func(w *response.Writer, req *request.Request) {
+		panic("boom")
+	}), Recover(logger))
+
+	out := serveHandler(t, handler, "GET /explode HTTP/1.1\r\n\r\n")
+	assert.True(t, strings.HasPrefix(out, "HTTP/1.1 500 Internal Server Error\r\n"))
+	assert.Contains(t, out, "connection: close\r\n")
+	assert.Contains(t, logs.String(), "panic serving GET /explode: boom")
+}
+
+func TestRequestIDAndLogger(t *testing.T) {
+	var logs bytes.Buffer
+	logger := log.New(&logs, "", 0)
+
+	var seen string
+	handler := Chain(HandlerFunc(func(w *response.Writer, req *request.Request) {
+		seen = req.Headers.Get("x-request-id")
+		echoTarget(w, req)
+	}), RequestID(), Logger(logger), Timing())
+
+	out := serveHandler(t, handler, "GET /hello HTTP/1.1\r\n\r\n")
+	assert.Len(t, seen, 32)
+	assert.Contains(t, out, "x-request-id: "+seen+"\r\n")
+	assert.Contains(t, out, "x-response-time: ")
+	assert.Contains(t, logs.String(), "["+seen+"] GET /hello 200 6B ")
+
+	out = serveHandler(t, handler, "GET /hello HTTP/1.1\r\nX-Request-Id: abc-123\r\n\r\n")
+	assert.Equal(t, "abc-123", seen)
+	assert.Contains(t, out, "x-request-id: abc-123\r\n")
+}
@@ -0,0 +1,255 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"unicode"
+
+	"surya.httpfromtcp/internal/headers"
+	"surya.httpfromtcp/internal/request"
+	"surya.httpfromtcp/internal/response"
+)
+
+const (
+	segmentLiteral = iota
+	segmentWildcard
+	segmentTail
+)
+
+type Router struct {
+	routes []*route
+	keys   map[string]bool
+}
+
+type route struct {
+	method   string
+	segments []segment
+	handler  Handler
+}
+
+type segment struct {
+	kind  int
+	value string
+}
+
+func NewRouter() *Router {
+	return &Router{
+		keys: make(map[string]bool),
+	}
+}
+
+func (rt *Router) Handle(pattern string, handler Handler) {
+	r, err := parsePattern(pattern)
+	if err != nil {
+		panic(fmt.Sprintf("server: invalid pattern %q: %v", pattern, err))
+	}
+
+	key := r.key()
+	if rt.keys[key] {
+		panic(fmt.Sprintf("server: pattern %q conflicts with an existing route", pattern))
+	}
+	rt.keys[key] = true
+
+	r.handler = handler
+	rt.routes = append(rt.routes, r)
+}
+
+func (rt *Router) HandleFunc(pattern string, handler func(w *response.Writer, req *request.Request)) {
+	rt.Handle(pattern, HandlerFunc(handler))
+}
+
+func (rt *Router) ServeHTTP(w *response.Writer, req *request.Request) {
+	method := req.RequestLine.Method
+	segments, ok := splitPath(req.RawPath())
+	if !ok {
+		writeError(w, response.StatusNotFound, "not found", nil)
+		return
+	}
+
+	var best *route
+	var bestValues map[string]string
+	var allowed []string
+
+	for _, r := range rt.routes {
+		values, ok := r.match(segments)
+		if !ok {
+			continue
+		}
+
+		if !r.allows(method) {
+			allowed = append(allowed, r.method)
+			if r.method == "GET" {
+				allowed = append(allowed, "HEAD")
+			}
+			continue
+		}
+
+		if best == nil || r.moreSpecific(best) {
+			best = r
+			bestValues = values
+		}
+	}
+
+	if best == nil {
+		if len(allowed) == 0 {
+			writeError(w, response.StatusNotFound, "not found", nil)
+			return
+		}
+
+		slices.Sort(allowed)
+		h := headers.NewHeaders()
+		h.Set("allow", strings.Join(slices.Compact(allowed), ", "))
+		writeError(w, response.StatusMethodNotAllowed, "method not allowed", h)
+		return
+	}
+
+	for name, value := range bestValues {
+		req.SetPathValue(name, value)
+	}
+
+	best.handler.ServeHTTP(w, req)
+}
+
+func parsePattern(pattern string) (*route, error) {
+	r := &route{}
+
+	path := pattern
+	if method, rest, found := strings.Cut(pattern, " "); found {
+		if method == "" {
+			return nil, errors.New("empty method")
+		}
+		for _, ch := range method {
+			if !unicode.IsUpper(ch) {
+				return nil, errors.New("method must contain only capital letters")
+			}
+		}
+		r.method = method
+		path = strings.TrimLeft(rest, " ")
+	}
+
+	if !strings.HasPrefix(path, "/") {
+		return nil, errors.New("path must begin with /")
+	}
+
+	parts := strings.Split(path[1:], "/")
+	names := make(map[string]bool)
+
+	for i, part := range parts {
+		if !strings.HasPrefix(part, "{") || !strings.HasSuffix(part, "}") {
+			if strings.ContainsAny(part, "{}") {
+				return nil, fmt.Errorf("wildcard must be a whole segment: %q", part)
+			}
+			r.segments = append(r.segments, segment{kind: segmentLiteral, value: part})
+			continue
+		}
+
+		name := part[1 : len(part)-1]
+		kind := segmentWildcard
+		if strings.HasSuffix(name, "...") {
+			if i != len(parts)-1 {
+				return nil, errors.New("tail wildcard must be the last segment")
+			}
+			name = strings.TrimSuffix(name, "...")
+			kind = segmentTail
+		}
+
+		if name == "" || strings.ContainsAny(name, "{}") {
+			return nil, fmt.Errorf("invalid wildcard name: %q", part)
+		}
+
+		if names[name] {
+			return nil, fmt.Errorf("duplicate wildcard name: %q", name)
+		}
+		names[name] = true
+
+		r.segments = append(r.segments, segment{kind: kind, value: name})
+	}
+
+	return r, nil
+}
+
+func splitPath(rawPath string) ([]string, bool) {
+	if !strings.HasPrefix(rawPath, "/") {
+		return nil, false
+	}
+
+	segments := strings.Split(rawPath[1:], "/")
+	for i, seg := range segments {
+		decoded, err := url.PathUnescape(seg)
+		if err != nil {
+			return nil, false
+		}
+		segments[i] = decoded
+	}
+
+	return segments, true
+}
+
+func (r *route) key() string {
+	var b strings.Builder
+	b.WriteString(r.method)
+	for _, seg := range r.segments {
+		b.WriteByte('/')
+		switch seg.kind {
+		case segmentLiteral:
+			b.WriteString(seg.value)
+		case segmentWildcard:
+			b.WriteString("{}")
+		case segmentTail:
+			b.WriteString("{...}")
+		}
+	}
+	return b.String()
+}
+
+func (r *route) match(segments []string) (map[string]string, bool) {
+	values := make(map[string]string)
+
+	for i, seg := range r.segments {
+		if i >= len(segments) {
+			return nil, false
+		}
+
+		switch seg.kind {
+		case segmentTail:
+			values[seg.value] = strings.Join(segments[i:], "/")
+			return values, true
+		case segmentWildcard:
+			if segments[i] == "" {
+				return nil, false
+			}
+			values[seg.value] = segments[i]
+		default:
+			if segments[i] != seg.value {
+				return nil, false
+			}
+		}
+	}
+
+	if len(segments) != len(r.segments) {
+		return nil, false
+	}
+
+	return values, true
+}
+
+func (r *route) allows(method string) bool {
+	return r.method == "" || r.method == method || (r.method == "GET" && method == "HEAD")
+}
+
+func (r *route) moreSpecific(other *route) bool {
+	for i := 0; i < len(r.segments) && i < len(other.segments); i++ {
+		if r.segments[i].kind != other.segments[i].kind {
+			return r.segments[i].kind < other.segments[i].kind
+		}
+	}
+
+	if len(r.segments) != len(other.segments) {
+		return len(r.segments) > len(other.segments)
+	}
+
+	return r.method != "" && other.method == ""
+}
@@ -0,0 +1,148 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"surya.httpfromtcp/internal/request"
+	"surya.httpfromtcp/internal/response"
+)
+
+func serveRouter(t *testing.T, router *Router, method, target string) string {
+	t.Helper()
+
+	req, err := request.RequestFromReader(strings.NewReader(method + " " + target + " HTTP/1.1\r\nHost: localhost\r\n\r\n"))
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	router.ServeHTTP(response.NewWriter(&buf), req)
+	return buf.String()
+}
+
+func namedHandler(name string) HandlerFunc {
+	return func(w *response.Writer, req *request.Request) {
+		body := []byte(name + " id=" + req.PathValue("id") + " path=" + req.PathValue("path"))
+		w.WriteStatusLine(response.StatusOK)
+		w.WriteHeaders(response.GetDefaultHeaders(len(body)))
+		w.WriteBody(body)
+	}
+}
+
+func TestRouter(t *testing.T) {
+	router := NewRouter()
+	router.Handle("GET /users/{id}", namedHandler("get-user"))
+	router.Handle("DELETE /users/{id}", namedHandler("delete-user"))
+	router.Handle("GET /users/me", namedHandler("me"))
+	router.Handle("/static/{path...}", namedHandler("static"))
+	router.Handle("POST /static/upload", namedHandler("upload"))
+	router.Handle("/", namedHandler("root"))
+
+	out := serveRouter(t, router, "GET", "/users/42?full=1")
+	assert.True(t, strings.HasPrefix(out, "HTTP/1.1 200 OK\r\n"))
+	assert.True(t, strings.HasSuffix(out, "get-user id=42 path="))
+
+	out = serveRouter(t, router, "HEAD", "/users/42")
+	assert.True(t, strings.HasSuffix(out, "get-user id=42 path="))
+
+	out = serveRouter(t, router, "DELETE", "/users/42")
+	assert.True(t, strings.HasSuffix(out, "delete-user id=42 path="))
+
+	out = serveRouter(t, router, "GET", "/users/me")
+	assert.True(t, strings.HasSuffix(out, "me id= path="))
+
+	out = serveRouter(t, router, "PUT", "/static/css/site.css")
+	assert.True(t, strings.HasSuffix(out, "static id= path=css/site.css"))
+
+	out = serveRouter(t, router, "POST", "/static/upload")
+	assert.True(t, strings.HasSuffix(out, "upload id= path="))
+
+	out = serveRouter(t, router, "GET", "/static/upload")
+	assert.True(t, strings.HasSuffix(out, "static id= path=upload"))
+
+	out = serveRouter(t, router, "GET", "/")
+	assert.True(t, strings.HasSuffix(out, "root id= path="))
+
+	out = serveRouter(t, router, "PUT", "/users/42")
+	assert.True(t, strings.HasPrefix(out, "HTTP/1.1 405 Method Not Allowed\r\n"))
+	assert.Contains(t, out, "allow: DELETE, GET, HEAD\r\n")
+
+	out = serveRouter(t, router, "GET", "/users")
+	assert.True(t, strings.HasPrefix(out, "HTTP/1.1 404 Not Found\r\n"))
+
+	out = serveRouter(t, router, "GET", "/users/")
+	assert.True(t, strings.HasPrefix(out, "HTTP/1.1 404 Not Found\r\n"))
+
+	out = serveRouter(t, router, "GET", "/users/42/posts")
+	assert.True(t, strings.HasPrefix(out, "HTTP/1.1 404 Not Found\r\n"))
+}
+
+func TestRouterInvalidPatterns(t *testing.T) {
+	for _, pattern := range []string{
+		"users",
+		"get /users",
+		"GET /users/{id",
+		"GET /users/x{id}",
+		"GET /files/{path...}/edit",
+		"GET /{}",
+		"GET /{id}/{id}",
+	} {
+		assert.Panics(t, func() { NewRouter().Handle(pattern, namedHandler("x")) }, pattern)
+	}
+
+	router := NewRouter()
+	router.Handle("GET /users/{id}", namedHandler("a"))
+	assert.Panics(t, func() { router.Handle("GET /users/{name}", namedHandler("b")) })
+	assert.NotPanics(t, func() { router.Handle("/users/{name}", namedHandler("c")) })
+}
+
+func TestRouterDecodesPath(t *testing.T) {
+	router := NewRouter()
+	router.Handle("GET /users/{id}", namedHandler("get-user"))
+	router.Handle("GET /files/{path...}", namedHandler("files"))
+
+	out := serveRouter(t, router, "GET", "/users/jane%20doe")
+	assert.True(t, strings.HasSuffix(out, "get-user id=jane doe path="))
+
+	out = serveRouter(t, router, "GET", "/users/a%2Fb")
+	assert.True(t, strings.HasSuffix(out, "get-user id=a/b path="))
+
+	out = serveRouter(t, router, "GET", "http://localhost/files/x/y.txt")
+	assert.True(t, strings.HasSuffix(out, "files id= path=x/y.txt"))
+
+	out = serveRouter(t, router, "OPTIONS", "*")
+	assert.True(t, strings.HasPrefix(out, "HTTP/1.1 404 Not Found\r\n"))
+}
+
+func TestRouterHeadOmitsBody(t *testing.T) {
+	router := NewRouter()
+	router.Handle("GET /items/{id}", namedHandler("item"))
+
+	conn := startServer(t, router)
+	reader := bufio.NewReader(conn)
+
+	_, err := conn.Write([]byte("HEAD /items/7 HTTP/1.1\r\nHost: localhost\r\n\r\n" +
+		"GET /items/8 HTTP/1.1\r\nHost: localhost\r\n\r\n"))
+	require.NoError(t, err)
+
+	var head strings.Builder
+	for {
+		line, err := reader.ReadString('\n')
+		require.NoError(t, err)
+		head.WriteString(line)
+		if line == "\r\n" {
+			break
+		}
+	}
+	assert.True(t, strings.HasPrefix(head.String(), "HTTP/1.1 200 OK\r\n"))
+	assert.Contains(t, head.String(), "content-length: 15\r\n")
+	assert.NotContains(t, head.String(), "connection: close")
+
+	head2, body := readResponse(t, reader)
+	assert.True(t, strings.HasPrefix(head2, "HTTP/1.1 200 OK\r\n"), head2)
+	assert.Equal(t, "item id=8 path=", body)
+}
@@ -1,31 +1,138 @@
 package server
 
 import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"io"
 	"net"
+	"os"
+	"strings"
+	"sync"
 	"sync/atomic"
+	"time"
 
+	"surya.httpfromtcp/internal/headers"
 	"surya.httpfromtcp/internal/request"
 	"surya.httpfromtcp/internal/response"
 )
 
-type Handler func(w *response.Writer, req *request.Request)
+const (
+	DefaultIdleTimeout       = 2 * time.Minute
+	DefaultReadHeaderTimeout = 10 * time.Second
+	shutdownPollInterval     = 50 * time.Millisecond
+	lingerTimeout            = 500 * time.Millisecond
+	newConnGrace             = 5 * time.Second
+)
+
+const (
+	connStateNew = iota
+	connStateActive
+	connStateIdle
+)
+
+type connState struct {
+	state int
+	since time.Time
+}
+
+type Handler interface {
+	ServeHTTP(w *response.Writer, req *request.Request)
+}
+
+type HandlerFunc func(w *response.Writer, req *request.Request)
+
+func (f HandlerFunc) ServeHTTP(w *response.Writer, req *request.Request) {
+	f(w, req)
+}
+
+type Option func(*Server)
+
+func WithIdleTimeout(d time.Duration) Option {
+	return func(s *Server) {
+		s.idleTimeout = d
+	}
+}
+
+func WithReadHeaderTimeout(d time.Duration) Option {
+	return func(s *Server) {
+		s.readHeaderTimeout = d
+	}
+}
+
+func WithReadTimeout(d time.Duration) Option {
+	return func(s *Server) {
+		s.readTimeout = d
+	}
+}
+
+func WithWriteTimeout(d time.Duration) Option {
+	return func(s *Server) {
+		s.writeTimeout = d
+	}
+}
+
+func WithExpectContinue(fn func(req *request.Request) bool) Option {
+	return func(s *Server) {
+		s.expectContinue = fn
+	}
+}
+
+func WithServerName(name string) Option {
+	return func(s *Server) {
+		s.serverName = name
+	}
+}
+
+func WithDefaultHeaders(h *headers.Headers) Option {
+	return func(s *Server) {
+		s.defaultHeaders = h.Clone()
+	}
+}
+
+func WithLimits(limits request.Limits) Option {
+	return func(s *Server) {
+		s.limits = limits
+	}
+}
 
 type Server struct {
-	listener net.Listener
-	closed   atomic.Bool
-	handler  Handler
+	listener          net.Listener
+	closed            atomic.Bool
+	handler           Handler
+	idleTimeout       time.Duration
+	readHeaderTimeout time.Duration
+	readTimeout       time.Duration
+	writeTimeout      time.Duration
+	limits            request.Limits
+	expectContinue    func(req *request.Request) bool
+	serverName        string
+	defaultHeaders    *headers.Headers
+	dates             dateCache
+	mu                sync.Mutex
+	conns             map[net.Conn]connState
 }
 
-func Serve(port int, handler Handler) (*Server, error) {
+func Serve(port int, handler Handler, opts ...Option) (*Server, error) {
 	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
 	if err != nil {
 		return nil, err
 	}
 
 	s := &Server{
-		listener: listener,
-		handler:  handler,
+		listener:          listener,
+		handler:           handler,
+		idleTimeout:       DefaultIdleTimeout,
+		readHeaderTimeout: DefaultReadHeaderTimeout,
+		limits:            request.DefaultLimits,
+		conns:             make(map[net.Conn]connState),
+	}
+
+	for _, opt := range opts {
+		opt(s)
 	}
 
 	go s.listen()
@@ -33,9 +140,75 @@ func Serve(port int, handler Handler) (*Server, error) {
 	return s, nil
 }
 
+func (s *Server) Addr() net.Addr {
+	return s.listener.Addr()
+}
+
 func (s *Server) Close() error {
 	s.closed.Store(true)
-	return s.listener.Close()
+	err := s.listener.Close()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for conn := range s.conns {
+		conn.Close()
+		delete(s.conns, conn)
+	}
+
+	return err
+}
+
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.closed.Store(true)
+	err := s.listener.Close()
+
+	ticker := time.NewTicker(shutdownPollInterval)
+	defer ticker.Stop()
+
+	for {
+		if s.closeIdleConns() {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			s.Close()
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Server) closeIdleConns() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for conn, cs := range s.conns {
+		if cs.state == connStateIdle || (cs.state == connStateNew && time.Since(cs.since) > newConnGrace) {
+			conn.Close()
+			delete(s.conns, conn)
+		}
+	}
+
+	return len(s.conns) == 0
+}
+
+// setConnState reports false when Close or Shutdown already dropped conn.
+func (s *Server) setConnState(conn net.Conn, state int) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.conns[conn]; !ok && state != connStateNew {
+		return false
+	}
+	s.conns[conn] = connState{state: state, since: time.Now()}
+	return true
+}
+
+func (s *Server) removeConn(conn net.Conn) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.conns, conn)
 }
 
 func (s *Server) listen() {
@@ -48,18 +221,212 @@ func (s *Server) listen() {
 			continue
 		}
 
+		s.setConnState(conn, connStateNew)
 		go s.handle(conn)
 	}
 }
 
 func (s *Server) handle(conn net.Conn) {
+	defer s.removeConn(conn)
 	defer conn.Close()
 
-	req, err := request.RequestFromReader(conn)
-	if err != nil {
+	reader := bufio.NewReaderSize(conn, s.limits.BufferSize())
+	for {
+		if !s.waitForRequest(conn, reader) || !s.setConnState(conn, connStateActive) {
+			return
+		}
+
+		start := time.Now()
+		conn.SetReadDeadline(deadline(start, s.headerTimeout()))
+		conn.SetWriteDeadline(deadline(start, s.writeTimeout))
+
+		writer := response.NewWriter(conn)
+		s.setDefaultHeaders(writer.Header(), start)
+		writer.OnWriteHeaders(func(h *headers.Headers) {
+			if s.closed.Load() {
+				h.Set("connection", "close")
+			}
+		})
+
+		req, err := request.RequestFromReaderWithLimits(reader, s.limits)
+		if err != nil {
+			if statusCode, ok := statusForParseError(err); ok {
+				h := headers.NewHeaders()
+				h.Set("connection", "close")
+				writeError(writer, statusCode, err.Error(), h)
+			}
+			return
+		}
+
+		writer.SetRequestVersion(req.RequestLine.HttpVersion)
+		writer.SetRequestMethod(req.RequestLine.Method)
+		writer.OnWriteHeaders(func(h *headers.Headers) {
+			switch {
+			case !req.KeepAlive() || !req.BodyDrainable():
+				h.Set("connection", "close")
+			case req.RequestLine.HttpVersion == "1.0" && !h.HasToken("connection", "close"):
+				h.Set("connection", "keep-alive")
+			}
+		})
+
+		if req.Headers.Has("expect") && req.RequestLine.HttpVersion != "1.0" {
+			if !s.acceptExpectation(req) {
+				h := headers.NewHeaders()
+				h.Set("connection", "close")
+				writeError(writer, response.StatusExpectationFailed, "expectation failed", h)
+				return
+			}
+
+			body := &continueBody{ReadCloser: req.Body, w: writer}
+			req.Body = body
+			writer.OnWriteHeaders(func(h *headers.Headers) {
+				if !body.sent && req.BodyPending() {
+					h.Set("connection", "close")
+				}
+			})
+		}
+
+		conn.SetReadDeadline(deadline(start, s.readTimeout))
+
+		s.handler.ServeHTTP(writer, req)
+		req.RemoveTempFiles()
+
+		if !writer.HeadersWritten() {
+			writer = response.NewWriter(conn)
+			s.setDefaultHeaders(writer.Header(), start)
+			writer.SetRequestVersion(req.RequestLine.HttpVersion)
+			writer.SetRequestMethod(req.RequestLine.Method)
+			h := headers.NewHeaders()
+			h.Set("connection", "close")
+			writeError(writer, response.StatusInternalServerError, "internal server error", h)
+			if !req.BodyDrainable() {
+				lingerClose(conn)
+			}
+			return
+		}
+
+		if err := writer.Finish(); err != nil {
+			return
+		}
+
+		if !req.KeepAlive() || !writer.KeepAlive() {
+			if !req.BodyDrainable() {
+				lingerClose(conn)
+			}
+			return
+		}
+
+		if err := req.Body.Close(); err != nil {
+			return
+		}
+
+		if s.closed.Load() || !s.setConnState(conn, connStateIdle) {
+			return
+		}
+	}
+}
+
+// lingerClose stops the client from seeing a reset before it reads the
+// response when its request body is still arriving.
+func lingerClose(conn net.Conn) {
+	cw, ok := conn.(interface{ CloseWrite() error })
+	if !ok || cw.CloseWrite() != nil {
 		return
 	}
 
-	writer := response.NewWriter(conn)
-	s.handler(writer, req)
+	conn.SetReadDeadline(time.Now().Add(lingerTimeout))
+	io.Copy(io.Discard, conn)
+}
+
+func (s *Server) setDefaultHeaders(h *headers.Headers, now time.Time) {
+	h.Set("date", s.dates.get(now))
+	if s.serverName != "" {
+		h.Set("server", s.serverName)
+	}
+	for key := range s.defaultHeaders.All() {
+		h.Del(key)
+	}
+	for key, value := range s.defaultHeaders.All() {
+		h.Add(key, value)
+	}
+}
+
+func (s *Server) waitForRequest(conn net.Conn, reader *bufio.Reader) bool {
+	conn.SetReadDeadline(deadline(time.Now(), s.idleTimeout))
+
+	if _, err := reader.Peek(1); err != nil {
+		return false
+	}
+
+	return true
+}
+
+func (s *Server) acceptExpectation(req *request.Request) bool {
+	if !strings.EqualFold(req.Headers.Get("expect"), "100-continue") || len(req.Headers.Values("expect")) > 1 {
+		return false
+	}
+	return s.expectContinue == nil || s.expectContinue(req)
+}
+
+func (s *Server) headerTimeout() time.Duration {
+	if s.readHeaderTimeout > 0 {
+		return s.readHeaderTimeout
+	}
+	return s.readTimeout
+}
+
+func deadline(start time.Time, timeout time.Duration) time.Time {
+	if timeout <= 0 {
+		return time.Time{}
+	}
+	return start.Add(timeout)
+}
+
+func statusForParseError(err error) (response.StatusCode, bool) {
+	switch {
+	case errors.Is(err, os.ErrDeadlineExceeded):
+		return response.StatusRequestTimeout, true
+	case errors.Is(err, request.ErrBadRequestLine),
+		errors.Is(err, request.ErrBadRequestTarget),
+		errors.Is(err, request.ErrBadHeader),
+		errors.Is(err, request.ErrBadContentLength),
+		errors.Is(err, request.ErrBadTransferEncoding),
+		errors.Is(err, request.ErrBadChunk):
+		return response.StatusBadRequest, true
+	case errors.Is(err, request.ErrUnsupportedTransferCoding):
+		return response.StatusNotImplemented, true
+	case errors.Is(err, request.ErrUnsupportedVersion):
+		return response.StatusHTTPVersionNotSupported, true
+	case errors.Is(err, request.ErrRequestLineTooLong):
+		return response.StatusURITooLong, true
+	case errors.Is(err, request.ErrHeaderTooLarge):
+		return response.StatusRequestHeaderFieldsTooLarge, true
+	case errors.Is(err, request.ErrBodyTooLarge):
+		return response.StatusContentTooLarge, true
+	default:
+		return 0, false
+	}
+}
+
+func writeError(w *response.Writer, statusCode response.StatusCode, message string, extra *headers.Headers) error {
+	body := []byte(message + "\n")
+	h := response.GetDefaultHeaders(len(body))
+	for key, value := range extra.All() {
+		h.Set(key, value)
+	}
+
+	if err := w.WriteStatusLine(statusCode); err != nil {
+		return err
+	}
+	if err := w.WriteHeaders(h); err != nil {
+		return err
+	}
+	_, err := w.WriteBody(body)
+	return err
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
 }
@@ -0,0 +1,635 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"surya.httpfromtcp/internal/headers"
+	"surya.httpfromtcp/internal/request"
+	"surya.httpfromtcp/internal/response"
+)
+
+func startServer(t *testing.T, handler Handler, opts ...Option) net.Conn {
+	t.Helper()
+
+	srv, err := Serve(0, handler, opts...)
+	require.NoError(t, err)
+	t.Cleanup(func() { srv.Close() })
+
+	conn, err := net.Dial("tcp", srv.Addr().String())
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	return conn
+}
+
+func readResponse(t *testing.T, reader *bufio.Reader) (string, string) {
+	t.Helper()
+
+	var head strings.Builder
+	contentLength := 0
+	for {
+		line, err := reader.ReadString('\n')
+		require.NoError(t, err)
+		head.WriteString(line)
+		if line == "\r\n" {
+			break
+		}
+		if name, value, ok := strings.Cut(line, ":"); ok && strings.EqualFold(name, "content-length") {
+			contentLength, err = strconv.Atoi(strings.TrimSpace(value))
+			require.NoError(t, err)
+		}
+	}
+
+	body := make([]byte, contentLength)
+	_, err := io.ReadFull(reader, body)
+	require.NoError(t, err)
+
+	return head.String(), string(body)
+}
+
+func echoTarget(w *response.Writer, req *request.Request) {
+	body := []byte(req.RequestLine.RequestTarget)
+	w.WriteStatusLine(response.StatusOK)
+	w.WriteHeaders(response.GetDefaultHeaders(len(body)))
+	w.WriteBody(body)
+}
+
+func TestKeepAlive(t *testing.T) {
+	conn := startServer(t, HandlerFunc(echoTarget))
+	reader := bufio.NewReader(conn)
+
+	_, err := conn.Write([]byte("GET /first HTTP/1.1\r\nHost: localhost\r\n\r\n"))
+	require.NoError(t, err)
+	head, body := readResponse(t, reader)
+	assert.True(t, strings.HasPrefix(head, "HTTP/1.1 200 OK\r\n"))
+	assert.Equal(t, "/first", body)
+
+	_, err = conn.Write([]byte("GET /second HTTP/1.1\r\nHost: localhost\r\nConnection: close\r\n\r\n"))
+	require.NoError(t, err)
+	_, body = readResponse(t, reader)
+	assert.Equal(t, "/second", body)
+
+	_, err = reader.ReadByte()
+	assert.ErrorIs(t, err, io.EOF)
+}
+
+func TestKeepAliveHandlerClose(t *testing.T) {
+	conn := startServer(t, HandlerFunc(func(w *response.Writer, req *request.Request) {
+		h := response.GetDefaultHeaders(0)
+		h.Set("connection", "close")
+		w.WriteStatusLine(response.StatusOK)
+		w.WriteHeaders(h)
+	}))
+	reader := bufio.NewReader(conn)
+
+	_, err := conn.Write([]byte("GET / HTTP/1.1\r\nHost: localhost\r\n\r\n"))
+	require.NoError(t, err)
+	readResponse(t, reader)
+
+	_, err = reader.ReadByte()
+	assert.ErrorIs(t, err, io.EOF)
+}
+
+func TestKeepAliveIdleTimeout(t *testing.T) {
+	conn := startServer(t, HandlerFunc(echoTarget), WithIdleTimeout(50*time.Millisecond))
+	reader := bufio.NewReader(conn)
+
+	_, err := conn.Write([]byte("GET / HTTP/1.1\r\nHost: localhost\r\n\r\n"))
+	require.NoError(t, err)
+	readResponse(t, reader)
+
+	_, err = reader.ReadByte()
+	assert.ErrorIs(t, err, io.EOF)
+}
+
+func TestKeepAliveChunked(t *testing.T) {
+	conn := startServer(t, HandlerFunc(func(w *response.Writer, req *request.Request) {
+		h := response.GetDefaultHeaders(0)
+		h.Del("content-length")
+		h.Set("transfer-encoding", "chunked")
+		w.WriteStatusLine(response.StatusOK)
+		w.WriteHeaders(h)
+		w.WriteChunkedBody([]byte(req.RequestLine.RequestTarget))
+	}))
+	reader := bufio.NewReader(conn)
+
+	for _, target := range []string{"/one", "/two"} {
+		_, err := conn.Write([]byte("GET " + target + " HTTP/1.1\r\nHost: localhost\r\n\r\n"))
+		require.NoError(t, err)
+		head, _ := readResponse(t, reader)
+		assert.Contains(t, head, "transfer-encoding: chunked\r\n")
+
+		body := make([]byte, len("4\r\n"+target+"\r\n0\r\n\r\n"))
+		_, err = io.ReadFull(reader, body)
+		require.NoError(t, err)
+		assert.Equal(t, "4\r\n"+target+"\r\n0\r\n\r\n", string(body))
+	}
+}
+
+func TestKeepAliveUnreadBody(t *testing.T) {
+	conn := startServer(t, HandlerFunc(echoTarget))
+	reader := bufio.NewReader(conn)
+
+	_, err := conn.Write([]byte("POST /first HTTP/1.1\r\nHost: localhost\r\nContent-Length: 11\r\n\r\nhello world"))
+	require.NoError(t, err)
+	_, body := readResponse(t, reader)
+	assert.Equal(t, "/first", body)
+
+	_, err = conn.Write([]byte("GET /second HTTP/1.1\r\nHost: localhost\r\n\r\n"))
+	require.NoError(t, err)
+	_, body = readResponse(t, reader)
+	assert.Equal(t, "/second", body)
+}
+
+func TestKeepAliveLargeUnreadBody(t *testing.T) {
+	conn := startServer(t, HandlerFunc(echoTarget))
+	reader := bufio.NewReader(conn)
+
+	_, err := conn.Write([]byte("POST /upload HTTP/1.1\r\nHost: localhost\r\nContent-Length: 1073741824\r\n\r\npartial"))
+	require.NoError(t, err)
+	head, body := readResponse(t, reader)
+	assert.Contains(t, head, "connection: close\r\n")
+	assert.Equal(t, "/upload", body)
+
+	_, err = reader.ReadByte()
+	assert.ErrorIs(t, err, io.EOF)
+}
+
+func TestKeepAliveLargeUnreadChunkedBody(t *testing.T) {
+	conn := startServer(t, HandlerFunc(echoTarget))
+	reader := bufio.NewReader(conn)
+
+	chunk := strings.Repeat("x", 4096)
+	var b strings.Builder
+	b.WriteString("POST /upload HTTP/1.1\r\nHost: localhost\r\nTransfer-Encoding: chunked\r\n\r\n")
+	for range 100 {
+		fmt.Fprintf(&b, "%x\r\n%s\r\n", len(chunk), chunk)
+	}
+	b.WriteString("0\r\n\r\nGET /next HTTP/1.1\r\nHost: localhost\r\n\r\n")
+
+	_, err := conn.Write([]byte(b.String()))
+	require.NoError(t, err)
+	head, body := readResponse(t, reader)
+	assert.Contains(t, head, "connection: close\r\n")
+	assert.Equal(t, "/upload", body)
+
+	_, err = reader.ReadByte()
+	assert.ErrorIs(t, err, io.EOF)
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		raw    string
+		status string
+	}{
+		{"get / HTTP/1.1\r\n\r\n", "400 Bad Request"},
+		{"GET /\r\n\r\n", "400 Bad Request"},
+		{"GET / HTTP/2.0\r\n\r\n", "505 HTTP Version Not Supported"},
+		{"GET / HTTP/3.0\r\n\r\n", "505 HTTP Version Not Supported"},
+		{"GET / HTTP/1.10\r\n\r\n", "400 Bad Request"},
+		{"POST / HTTP/1.0\r\nTransfer-Encoding: chunked\r\n\r\n0\r\n\r\n", "400 Bad Request"},
+		{"GET / HTTP/1.1\r\nHost localhost\r\n\r\n", "400 Bad Request"},
+		{"GET / HTTP/1.1\r\nX-A: a\rb\x00c\r\n\r\n", "400 Bad Request"},
+		{"POST / HTTP/1.1\r\nContent-Length: abc\r\n\r\n", "400 Bad Request"},
+		{"POST / HTTP/1.1\r\nContent-Length: -1\r\n\r\n", "400 Bad Request"},
+		{"POST / HTTP/1.1\r\nContent-Length: 4\r\nTransfer-Encoding: chunked\r\n\r\n0\r\n\r\n", "400 Bad Request"},
+		{"POST / HTTP/1.1\r\nTransfer-Encoding: gzip\r\n\r\n", "501 Not Implemented"},
+	}
+
+	for _, tt := range tests {
+		conn := startServer(t, HandlerFunc(echoTarget))
+		reader := bufio.NewReader(conn)
+
+		_, err := conn.Write([]byte(tt.raw))
+		require.NoError(t, err)
+		head, _ := readResponse(t, reader)
+		assert.True(t, strings.HasPrefix(head, "HTTP/1.1 "+tt.status+"\r\n"), head)
+
+		_, err = reader.ReadByte()
+		assert.ErrorIs(t, err, io.EOF)
+	}
+}
+
+func TestLimitErrors(t *testing.T) {
+	limits := request.Limits{
+		MaxRequestLineBytes: 32,
+		MaxHeaderCount:      2,
+		MaxHeaderBytes:      64,
+		MaxBodyBytes:        10,
+	}
+
+	tests := []struct {
+		raw    string
+		status string
+	}{
+		{"GET /" + strings.Repeat("a", 40) + " HTTP/1.1\r\n\r\n", "414 URI Too Long"},
+		{"GET / HTTP/1.1\r\nA: 1\r\nB: 2\r\nC: 3\r\n\r\n", "431 Request Header Fields Too Large"},
+		{"POST / HTTP/1.1\r\nContent-Length: 11\r\n\r\nhello world", "413 Content Too Large"},
+	}
+
+	for _, tt := range tests {
+		conn := startServer(t, HandlerFunc(echoTarget), WithLimits(limits))
+		reader := bufio.NewReader(conn)
+
+		_, err := conn.Write([]byte(tt.raw))
+		require.NoError(t, err)
+		head, _ := readResponse(t, reader)
+		assert.True(t, strings.HasPrefix(head, "HTTP/1.1 "+tt.status+"\r\n"), head)
+		assert.Contains(t, head, "connection: close\r\n")
+	}
+}
+
+func TestShutdown(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	srv, err := Serve(0, HandlerFunc(func(w *response.Writer, req *request.Request) {
+		if req.RequestLine.RequestTarget == "/slow" {
+			close(started)
+			<-release
+		}
+		echoTarget(w, req)
+	}))
+	require.NoError(t, err)
+	t.Cleanup(func() { srv.Close() })
+
+	idle, err := net.Dial("tcp", srv.Addr().String())
+	require.NoError(t, err)
+	defer idle.Close()
+	idle.SetDeadline(time.Now().Add(5 * time.Second))
+	idleReader := bufio.NewReader(idle)
+	_, err = idle.Write([]byte("GET /fast HTTP/1.1\r\nHost: localhost\r\n\r\n"))
+	require.NoError(t, err)
+	readResponse(t, idleReader)
+
+	active, err := net.Dial("tcp", srv.Addr().String())
+	require.NoError(t, err)
+	defer active.Close()
+	active.SetDeadline(time.Now().Add(5 * time.Second))
+	activeReader := bufio.NewReader(active)
+	_, err = active.Write([]byte("GET /slow HTTP/1.1\r\nHost: localhost\r\n\r\n"))
+	require.NoError(t, err)
+	<-started
+
+	done := make(chan error, 1)
+	go func() { done <- srv.Shutdown(context.Background()) }()
+
+	_, err = idleReader.ReadByte()
+	assert.ErrorIs(t, err, io.EOF)
+
+	select {
+	case <-done:
+		t.Fatal("shutdown returned before the active handler finished")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	close(release)
+	head, body := readResponse(t, activeReader)
+	assert.Contains(t, head, "connection: close\r\n")
+	assert.Equal(t, "/slow", body)
+	require.NoError(t, <-done)
+
+	_, err = net.Dial("tcp", srv.Addr().String())
+	assert.Error(t, err)
+}
+
+func TestShutdownServesNewConn(t *testing.T) {
+	srv, err := Serve(0, HandlerFunc(echoTarget))
+	require.NoError(t, err)
+	t.Cleanup(func() { srv.Close() })
+
+	conn, err := net.Dial("tcp", srv.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	time.Sleep(50 * time.Millisecond)
+
+	done := make(chan error, 1)
+	go func() { done <- srv.Shutdown(context.Background()) }()
+	time.Sleep(3 * shutdownPollInterval)
+
+	_, err = conn.Write([]byte("GET /late HTTP/1.1\r\nHost: localhost\r\n\r\n"))
+	require.NoError(t, err)
+	head, body := readResponse(t, bufio.NewReader(conn))
+	assert.True(t, strings.HasPrefix(head, "HTTP/1.1 200 OK\r\n"), head)
+	assert.Contains(t, head, "connection: close\r\n")
+	assert.Equal(t, "/late", body)
+	require.NoError(t, <-done)
+}
+
+func TestShutdownContextExpired(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	started := make(chan struct{})
+
+	srv, err := Serve(0, HandlerFunc(func(w *response.Writer, req *request.Request) {
+		close(started)
+		<-release
+	}))
+	require.NoError(t, err)
+
+	conn, err := net.Dial("tcp", srv.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	_, err = conn.Write([]byte("GET / HTTP/1.1\r\nHost: localhost\r\n\r\n"))
+	require.NoError(t, err)
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, srv.Shutdown(ctx), context.DeadlineExceeded)
+
+	_, err = conn.Read(make([]byte, 1))
+	assert.ErrorIs(t, err, io.EOF)
+}
+
+func TestReadHeaderTimeout(t *testing.T) {
+	conn := startServer(t, HandlerFunc(echoTarget), WithReadHeaderTimeout(50*time.Millisecond))
+	reader := bufio.NewReader(conn)
+
+	_, err := conn.Write([]byte("GET /slow HTTP/1.1\r\nHost: loc"))
+	require.NoError(t, err)
+
+	head, _ := readResponse(t, reader)
+	assert.True(t, strings.HasPrefix(head, "HTTP/1.1 408 Request Timeout\r\n"), head)
+	assert.Contains(t, head, "connection: close\r\n")
+
+	_, err = reader.ReadByte()
+	assert.ErrorIs(t, err, io.EOF)
+}
+
+func TestReadTimeout(t *testing.T) {
+	bodyErr := make(chan error, 1)
+	conn := startServer(t, HandlerFunc(func(w *response.Writer, req *request.Request) {
+		_, err := req.ReadBody()
+		bodyErr <- err
+	}), WithReadTimeout(100*time.Millisecond))
+
+	_, err := conn.Write([]byte("POST / HTTP/1.1\r\nHost: localhost\r\nContent-Length: 10\r\n\r\nabc"))
+	require.NoError(t, err)
+
+	select {
+	case err := <-bodyErr:
+		assert.ErrorIs(t, err, os.ErrDeadlineExceeded)
+	case <-time.After(2 * time.Second):
+		t.Fatal("body read did not time out")
+	}
+}
+
+func TestPipelining(t *testing.T) {
+	conn := startServer(t, HandlerFunc(func(w *response.Writer, req *request.Request) {
+		body, _ := req.ReadBody()
+		body = append([]byte(req.RequestLine.RequestTarget+":"), body...)
+		w.WriteStatusLine(response.StatusOK)
+		w.WriteHeaders(response.GetDefaultHeaders(len(body)))
+		w.WriteBody(body)
+	}))
+	reader := bufio.NewReader(conn)
+
+	_, err := conn.Write([]byte("GET /a HTTP/1.1\r\nHost: localhost\r\n\r\n" +
+		"POST /b HTTP/1.1\r\nHost: localhost\r\nContent-Length: 3\r\n\r\nxyz" +
+		"POST /c HTTP/1.1\r\nHost: localhost\r\nTransfer-Encoding: chunked\r\n\r\n2\r\nhi\r\n0\r\n\r\n" +
+		"GET /d HTTP/1.1\r\nHost: localhost\r\nConnection: close\r\n\r\n"))
+	require.NoError(t, err)
+
+	for _, expected := range []string{"/a:", "/b:xyz", "/c:hi", "/d:"} {
+		_, body := readResponse(t, reader)
+		assert.Equal(t, expected, body)
+	}
+
+	_, err = reader.ReadByte()
+	assert.ErrorIs(t, err, io.EOF)
+}
+
+func echoBody(w *response.Writer, req *request.Request) {
+	body, err := req.ReadBody()
+	if err != nil {
+		writeError(w, response.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	w.WriteStatusLine(response.StatusOK)
+	w.WriteHeaders(response.GetDefaultHeaders(len(body)))
+	w.WriteBody(body)
+}
+
+func TestExpectContinue(t *testing.T) {
+	conn := startServer(t, HandlerFunc(echoBody))
+	reader := bufio.NewReader(conn)
+
+	_, err := conn.Write([]byte("POST / HTTP/1.1\r\nHost: localhost\r\nExpect: 100-continue\r\nContent-Length: 5\r\n\r\n"))
+	require.NoError(t, err)
+	head, _ := readResponse(t, reader)
+	assert.Equal(t, "HTTP/1.1 100 Continue\r\n\r\n", head)
+
+	_, err = conn.Write([]byte("hello"))
+	require.NoError(t, err)
+	head, body := readResponse(t, reader)
+	assert.True(t, strings.HasPrefix(head, "HTTP/1.1 200 OK\r\n"))
+	assert.NotContains(t, head, "connection: close")
+	assert.Equal(t, "hello", body)
+
+	_, err = conn.Write([]byte("GET /next HTTP/1.1\r\nHost: localhost\r\n\r\n"))
+	require.NoError(t, err)
+	head, _ = readResponse(t, reader)
+	assert.True(t, strings.HasPrefix(head, "HTTP/1.1 200 OK\r\n"))
+}
+
+func TestExpectContinueUnreadBody(t *testing.T) {
+	conn := startServer(t, HandlerFunc(echoTarget))
+	reader := bufio.NewReader(conn)
+
+	_, err := conn.Write([]byte("POST /skip HTTP/1.1\r\nHost: localhost\r\nExpect: 100-continue\r\nContent-Length: 5\r\n\r\n"))
+	require.NoError(t, err)
+	head, body := readResponse(t, reader)
+	assert.True(t, strings.HasPrefix(head, "HTTP/1.1 200 OK\r\n"))
+	assert.Contains(t, head, "connection: close\r\n")
+	assert.Equal(t, "/skip", body)
+
+	_, err = reader.ReadByte()
+	assert.ErrorIs(t, err, io.EOF)
+}
+
+func TestExpectationFailed(t *testing.T) {
+	reject := WithExpectContinue(func(req *request.Request) bool {
+		return req.Headers.Get("content-length") != "1000000"
+	})
+
+	for _, raw := range []string{
+		"POST / HTTP/1.1\r\nHost: localhost\r\nExpect: 100-continue\r\nContent-Length: 1000000\r\n\r\n",
+		"POST / HTTP/1.1\r\nHost: localhost\r\nExpect: something-else\r\nContent-Length: 5\r\n\r\n",
+	} {
+		conn := startServer(t, HandlerFunc(echoBody), reject)
+		reader := bufio.NewReader(conn)
+
+		_, err := conn.Write([]byte(raw))
+		require.NoError(t, err)
+		head, _ := readResponse(t, reader)
+		assert.True(t, strings.HasPrefix(head, "HTTP/1.1 417 Expectation Failed\r\n"), head)
+		assert.Contains(t, head, "connection: close\r\n")
+	}
+}
+
+func TestHTTP10(t *testing.T) {
+	conn := startServer(t, HandlerFunc(echoTarget))
+	reader := bufio.NewReader(conn)
+
+	_, err := conn.Write([]byte("GET /first HTTP/1.0\r\nConnection: keep-alive\r\n\r\n"))
+	require.NoError(t, err)
+	head, body := readResponse(t, reader)
+	assert.True(t, strings.HasPrefix(head, "HTTP/1.1 200 OK\r\n"))
+	assert.Contains(t, head, "connection: keep-alive\r\n")
+	assert.Equal(t, "/first", body)
+
+	_, err = conn.Write([]byte("GET /second HTTP/1.0\r\n\r\n"))
+	require.NoError(t, err)
+	head, body = readResponse(t, reader)
+	assert.Contains(t, head, "connection: close\r\n")
+	assert.Equal(t, "/second", body)
+
+	_, err = reader.ReadByte()
+	assert.ErrorIs(t, err, io.EOF)
+}
+
+func TestHTTP10Chunked(t *testing.T) {
+	conn := startServer(t, HandlerFunc(func(w *response.Writer, req *request.Request) {
+		h := response.GetDefaultHeaders(0)
+		h.Del("content-length")
+		h.Set("transfer-encoding", "chunked")
+		h.Set("trailer", "x-checksum")
+		w.WriteStatusLine(response.StatusOK)
+		w.WriteHeaders(h)
+		w.WriteChunkedBody([]byte("hello "))
+		w.WriteChunkedBody([]byte("world"))
+		w.WriteChunkedBodyDone()
+		trailers := headers.NewHeaders()
+		trailers.Set("x-checksum", "abc")
+		w.WriteTrailers(trailers)
+	}))
+
+	_, err := conn.Write([]byte("GET / HTTP/1.0\r\nConnection: keep-alive\r\n\r\n"))
+	require.NoError(t, err)
+
+	out, err := io.ReadAll(conn)
+	require.NoError(t, err)
+	head, body, ok := strings.Cut(string(out), "\r\n\r\n")
+	require.True(t, ok)
+	assert.True(t, strings.HasPrefix(head, "HTTP/1.1 200 OK\r\n"))
+	assert.Contains(t, head, "connection: close")
+	assert.NotContains(t, head, "transfer-encoding")
+	assert.NotContains(t, head, "trailer")
+	assert.Equal(t, "hello world", body)
+}
+
+func TestHTTP10IgnoresExpect(t *testing.T) {
+	conn := startServer(t, HandlerFunc(echoBody))
+	reader := bufio.NewReader(conn)
+
+	_, err := conn.Write([]byte("POST / HTTP/1.0\r\nExpect: 100-continue\r\nContent-Length: 5\r\n\r\nhello"))
+	require.NoError(t, err)
+	head, body := readResponse(t, reader)
+	assert.True(t, strings.HasPrefix(head, "HTTP/1.1 200 OK\r\n"), head)
+	assert.Equal(t, "hello", body)
+}
+
+func TestDefaultHeaders(t *testing.T) {
+	defaults := headers.NewHeaders()
+	defaults.Set("x-frame-options", "DENY")
+	defaults.Set("cache-control", "no-store")
+
+	conn := startServer(t, HandlerFunc(func(w *response.Writer, req *request.Request) {
+		h := response.GetDefaultHeaders(0)
+		if req.Path() == "/override" {
+			h.Set("cache-control", "max-age=60")
+			h.Set("date", "Fri, 01 Mar 2024 12:00:00 GMT")
+			w.Header().Set("server", "handler")
+		}
+		w.WriteStatusLine(response.StatusOK)
+		w.WriteHeaders(h)
+	}), WithServerName("httpfromtcp"), WithDefaultHeaders(defaults))
+	reader := bufio.NewReader(conn)
+
+	_, err := conn.Write([]byte("GET / HTTP/1.1\r\nHost: localhost\r\n\r\n"))
+	require.NoError(t, err)
+	head, _ := readResponse(t, reader)
+	assert.Contains(t, head, "server: httpfromtcp\r\n")
+	assert.Contains(t, head, "x-frame-options: DENY\r\n")
+	assert.Contains(t, head, "cache-control: no-store\r\n")
+
+	_, date, ok := strings.Cut(head, "date: ")
+	require.True(t, ok)
+	date, _, _ = strings.Cut(date, "\r\n")
+	parsed, err := time.Parse(response.TimeFormat, date)
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now(), parsed, 2*time.Second)
+
+	_, err = conn.Write([]byte("GET /override HTTP/1.1\r\nHost: localhost\r\n\r\n"))
+	require.NoError(t, err)
+	head, _ = readResponse(t, reader)
+	assert.Contains(t, head, "server: handler\r\n")
+	assert.Contains(t, head, "cache-control: max-age=60\r\n")
+	assert.Contains(t, head, "date: Fri, 01 Mar 2024 12:00:00 GMT\r\n")
+	assert.Equal(t, 1, strings.Count(head, "cache-control"))
+	assert.Equal(t, 1, strings.Count(head, "date:"))
+}
+
+func TestDefaultHeadersOneValuePerField(t *testing.T) {
+	defaults := headers.NewHeaders()
+	defaults.Set("Server", "custom")
+	defaults.Set("x-frame-options", "DENY")
+	defaults.Add("link", "</a.css>; rel=preload")
+	defaults.Add("link", "</b.js>; rel=preload")
+
+	conn := startServer(t, HandlerFunc(func(w *response.Writer, req *request.Request) {
+		w.WriteStatusLine(response.StatusOK)
+		w.WriteHeaders(response.GetDefaultHeaders(0))
+	}), WithServerName("srv"), WithDefaultHeaders(defaults))
+	reader := bufio.NewReader(conn)
+
+	_, err := conn.Write([]byte("GET / HTTP/1.1\r\nHost: localhost\r\n\r\n"))
+	require.NoError(t, err)
+	head, _ := readResponse(t, reader)
+	assert.Equal(t, 1, strings.Count(strings.ToLower(head), "server:"))
+	assert.Contains(t, head, "Server: custom\r\n")
+	assert.Equal(t, 1, strings.Count(head, "x-frame-options"))
+	assert.Contains(t, head, "link: </a.css>; rel=preload\r\nlink: </b.js>; rel=preload\r\n")
+}
+
+func TestDateCache(t *testing.T) {
+	var c dateCache
+	now := time.Date(2024, time.March, 1, 12, 0, 0, 100, time.FixedZone("X", 3600))
+
+	assert.Equal(t, "Fri, 01 Mar 2024 11:00:00 GMT", c.get(now))
+	assert.Equal(t, "Fri, 01 Mar 2024 11:00:00 GMT", c.get(now.Add(500*time.Millisecond)))
+	assert.Equal(t, "Fri, 01 Mar 2024 11:00:01 GMT", c.get(now.Add(time.Second)))
+}
+
+func TestInvalidResponseHeaderSendsServerError(t *testing.T) {
+	conn := startServer(t, HandlerFunc(func(w *response.Writer, req *request.Request) {
+		h := response.GetDefaultHeaders(0)
+		h.Set("x-echo", "bad\r\nvalue")
+		w.WriteStatusLine(response.StatusOK)
+		w.WriteHeaders(h)
+	}))
+
+	_, err := conn.Write([]byte("GET / HTTP/1.1\r\nHost: localhost\r\n\r\n"))
+	require.NoError(t, err)
+
+	out, err := io.ReadAll(conn)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(out), "HTTP/1.1 500 Internal Server Error\r\n"), string(out))
+	assert.Contains(t, string(out), "connection: close\r\n")
+	assert.NotContains(t, string(out), "x-echo")
+}